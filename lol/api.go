@@ -0,0 +1,149 @@
+package xenblocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"xoon/miner"
+)
+
+// The lolMiner API is only served on localhost. It is read only, so it
+// needs no token.
+const (
+	API_HOST = "127.0.0.1"
+	API_PORT = 18089
+)
+
+// apiArgs enables the JSON API on the lolMiner command line
+func apiArgs() []string {
+	return []string{
+		"--apihost", API_HOST,
+		"--apiport", strconv.Itoa(API_PORT),
+	}
+}
+
+// apiSummary is the part of the lolMiner API answer shown in the app
+type apiSummary struct {
+	Software string `json:"Software"`
+	Session  struct {
+		Uptime int64 `json:"Uptime"`
+	} `json:"Session"`
+	Workers []struct {
+		Index    int     `json:"Index"`
+		Name     string  `json:"Name"`
+		Power    float64 `json:"Power"`
+		CoreTemp float64 `json:"Core_Temp"`
+		FanSpeed float64 `json:"Fan_Speed"`
+	} `json:"Workers"`
+	Algorithms []struct {
+		Algorithm         string    `json:"Algorithm"`
+		Pool              string    `json:"Pool"`
+		PerformanceFactor float64   `json:"Performance_Factor"`
+		TotalPerformance  float64   `json:"Total_Performance"`
+		TotalAccepted     int       `json:"Total_Accepted"`
+		TotalRejected     int       `json:"Total_Rejected"`
+		TotalStales       int       `json:"Total_Stales"`
+		WorkerPerformance []float64 `json:"Worker_Performance"`
+		WorkerAccepted    []int     `json:"Worker_Accepted"`
+		WorkerRejected    []int     `json:"Worker_Rejected"`
+		WorkerStales      []int     `json:"Worker_Stales"`
+	} `json:"Algorithms"`
+}
+
+// APIStats are the mining figures reported by the lolMiner API
+type APIStats struct {
+	Software  string
+	Algorithm string
+	Pool      string
+	Uptime    time.Duration
+	// Total hashrate in H/s
+	Hashrate float64
+	Accepted int
+	Rejected int
+	Stale    int
+	GPUs     []miner.DeviceStats
+}
+
+// APIClient reads the stats of the running lolMiner from its API
+type APIClient struct {
+	BaseURL string
+	client  *http.Client
+}
+
+// NewAPIClient creates a client for the API of the lolMiner started by the app
+func NewAPIClient() *APIClient {
+	return &APIClient{
+		BaseURL: fmt.Sprintf("http://%s:%d", API_HOST, API_PORT),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// GetStats polls the API for the totals and the figures of every GPU
+func (c *APIClient) GetStats() (*APIStats, error) {
+	resp, err := c.client.Get(c.BaseURL + "/")
+	if err != nil {
+		return nil, fmt.Errorf("lolMiner API unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lolMiner API returned %s", resp.Status)
+	}
+	var summary apiSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("error decoding lolMiner API: %v", err)
+	}
+
+	stats := &APIStats{
+		Software: summary.Software,
+		Uptime:   time.Duration(summary.Session.Uptime) * time.Second,
+	}
+
+	// 1. The telemetry of the GPUs
+	for _, worker := range summary.Workers {
+		stats.GPUs = append(stats.GPUs, miner.DeviceStats{
+			Index:       worker.Index,
+			Name:        worker.Name,
+			Temperature: worker.CoreTemp,
+			FanSpeed:    worker.FanSpeed,
+			Power:       worker.Power,
+		})
+	}
+
+	// 2. Hashrate and shares of the mined algorithm, in the order of the GPUs.
+	// The performance is in Performance_Unit, like Mh/s, with its factor to H/s.
+	if len(summary.Algorithms) > 0 {
+		algorithm := summary.Algorithms[0]
+		factor := algorithm.PerformanceFactor
+		if factor == 0 {
+			factor = 1
+		}
+
+		stats.Algorithm = algorithm.Algorithm
+		stats.Pool = algorithm.Pool
+		stats.Hashrate = algorithm.TotalPerformance * factor
+		stats.Accepted = algorithm.TotalAccepted
+		stats.Rejected = algorithm.TotalRejected
+		stats.Stale = algorithm.TotalStales
+
+		for i := range stats.GPUs {
+			gpu := &stats.GPUs[i]
+			if i < len(algorithm.WorkerPerformance) {
+				gpu.Hashrate = algorithm.WorkerPerformance[i] * factor
+			}
+			if i < len(algorithm.WorkerAccepted) {
+				gpu.Accepted = algorithm.WorkerAccepted[i]
+			}
+			if i < len(algorithm.WorkerRejected) {
+				gpu.Rejected = algorithm.WorkerRejected[i]
+			}
+			if i < len(algorithm.WorkerStales) {
+				gpu.Stale = algorithm.WorkerStales[i]
+			}
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,172 @@
+package xenblocks
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"xoon/miner"
+)
+
+// The API answer of lolMiner 1.88 mining FishHash on two GPUs
+const testSummaryJSON = `{
+    "Software": "lolMiner 1.88",
+    "Session": {
+        "Startup": 1714557600,
+        "Startup_String": "2024-05-01 10:00:00",
+        "Uptime": 5432,
+        "Last_Update": 1714563032,
+        "Active_GPUs": 2,
+        "Performance_Summary": 61.27,
+        "Performance_Unit": "Mh/s",
+        "Accepted": 57,
+        "Submitted": 59,
+        "TotalPower": 341.5
+    },
+    "Num_Workers": 2,
+    "Workers": [
+        {
+            "Index": 0,
+            "Name": "NVIDIA GeForce RTX 3070",
+            "Power": 142.3,
+            "CCLK": 1830,
+            "MCLK": 7000,
+            "Core_Temp": 61,
+            "Juc_Temp": 0,
+            "Mem_Temp": 0,
+            "Fan_Speed": 55,
+            "LHR_Unlock_Value": 0,
+            "PCIE_Address": "1:0"
+        },
+        {
+            "Index": 1,
+            "Name": "NVIDIA GeForce RTX 3080",
+            "Power": 199.2,
+            "CCLK": 1755,
+            "MCLK": 9251,
+            "Core_Temp": 74,
+            "Juc_Temp": 0,
+            "Mem_Temp": 0,
+            "Fan_Speed": 68,
+            "LHR_Unlock_Value": 0,
+            "PCIE_Address": "2:0"
+        }
+    ],
+    "Num_Algorithms": 1,
+    "Algorithms": [
+        {
+            "Algorithm": "FishHash",
+            "Algorithm_Appendix": "",
+            "Pool": "stratum+ssl://fishhash.unmineable.com:4444",
+            "User": "SOL:9wFFyRfZBsuAha4YcuxcXLKwMxJR43S7fPfQLusDBzvT.xoon#plxp-imd8",
+            "Worker": "",
+            "Performance_Unit": "Mh/s",
+            "Performance_Factor": 1000000,
+            "Total_Performance": 61.27,
+            "Total_Accepted": 57,
+            "Total_Rejected": 1,
+            "Total_Stales": 1,
+            "Total_Errors": 0,
+            "Worker_Performance": [25.11, 36.16],
+            "Worker_Accepted": [24, 33],
+            "Worker_Rejected": [0, 1],
+            "Worker_Stales": [1, 0],
+            "Worker_Errors": [0, 0]
+        }
+    ]
+}`
+
+func newTestAPIClient(t *testing.T, status int, body string) *APIClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return &APIClient{BaseURL: server.URL, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func TestGetStats(t *testing.T) {
+	stats, err := newTestAPIClient(t, http.StatusOK, testSummaryJSON).GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+
+	if stats.Software != "lolMiner 1.88" || stats.Algorithm != "FishHash" || stats.Uptime != 5432*time.Second {
+		t.Errorf("software %q, algorithm %q, uptime %s", stats.Software, stats.Algorithm, stats.Uptime)
+	}
+	if stats.Pool != "stratum+ssl://fishhash.unmineable.com:4444" {
+		t.Errorf("pool %q", stats.Pool)
+	}
+	// Mh/s with its factor to H/s
+	if math.Abs(stats.Hashrate-61.27e6) > 1e-3 {
+		t.Errorf("hashrate %v, want 61.27 MH/s", stats.Hashrate)
+	}
+	if stats.Accepted != 57 || stats.Rejected != 1 || stats.Stale != 1 {
+		t.Errorf("shares %d/%d/%d", stats.Accepted, stats.Rejected, stats.Stale)
+	}
+
+	want := []miner.DeviceStats{
+		{Index: 0, Name: "NVIDIA GeForce RTX 3070", Hashrate: 25.11e6, Accepted: 24, Rejected: 0, Stale: 1, Temperature: 61, FanSpeed: 55, Power: 142.3},
+		{Index: 1, Name: "NVIDIA GeForce RTX 3080", Hashrate: 36.16e6, Accepted: 33, Rejected: 1, Stale: 0, Temperature: 74, FanSpeed: 68, Power: 199.2},
+	}
+	if len(stats.GPUs) != len(want) {
+		t.Fatalf("%d GPUs, want %d", len(stats.GPUs), len(want))
+	}
+	for i := range want {
+		got := stats.GPUs[i]
+		if math.Abs(got.Hashrate-want[i].Hashrate) > 1e-3 {
+			t.Errorf("GPU %d hashrate %v, want %v", i, got.Hashrate, want[i].Hashrate)
+		}
+		got.Hashrate = want[i].Hashrate
+		if got != want[i] {
+			t.Errorf("GPU %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestGetStatsBeforeMining(t *testing.T) {
+	// While the DAG is built lolMiner knows the GPUs but has no algorithm figures
+	body := `{
+    "Software": "lolMiner 1.88",
+    "Session": {"Uptime": 12},
+    "Workers": [{"Index": 0, "Name": "NVIDIA GeForce RTX 3070", "Power": 60.1, "Core_Temp": 45, "Fan_Speed": 30}],
+    "Algorithms": [{"Algorithm": "FishHash", "Pool": "fishhash.unmineable.com:3333", "Performance_Factor": 0, "Total_Performance": 0}]
+}`
+	stats, err := newTestAPIClient(t, http.StatusOK, body).GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if len(stats.GPUs) != 1 || stats.GPUs[0].Hashrate != 0 || stats.GPUs[0].Temperature != 45 {
+		t.Fatalf("GPUs = %+v", stats.GPUs)
+	}
+	if stats.Hashrate != 0 {
+		t.Fatalf("hashrate %v, want 0", stats.Hashrate)
+	}
+}
+
+func TestGetStatsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"server error", http.StatusInternalServerError, "", "500 Internal Server Error"},
+		{"not JSON", http.StatusOK, "<html></html>", "error decoding lolMiner API"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestAPIClient(t, tt.status, tt.body).GetStats()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package xenblocks
+
+import (
+	"math"
+	"testing"
+	"xoon/miner"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		wantHashrate float64
+		wantAccepted int
+		wantRejected int
+	}{
+		{
+			name: "share lines count shares",
+			lines: []string{
+				"GPU 0: Share accepted (42 ms)",
+				"GPU 1: Share accepted (40 ms)",
+				"GPU 0: Share rejected (stale)",
+			},
+			wantAccepted: 2,
+			wantRejected: 1,
+		},
+		{
+			name: "statistics don't count as shares",
+			lines: []string{
+				"Shares accepted: 12, rejected: 1",
+				"Pool accepted the connection",
+			},
+		},
+		{
+			name:         "average speed",
+			lines:        []string{"Average speed (30s): 61.27 Mh/s"},
+			wantHashrate: 61.27e6,
+		},
+		{
+			name: "total speed of the table",
+			lines: []string{
+				"Average speed (30s): 30.1 Mh/s",
+				"Total                 61.27 Mh/s",
+			},
+			wantHashrate: 61.27e6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats miner.Stats
+			for _, line := range tt.lines {
+				Adapter{}.ParseLine(line, &stats)
+			}
+			if math.Abs(stats.Hashrate-tt.wantHashrate) > 1e-3 {
+				t.Errorf("hashrate = %v, want %v", stats.Hashrate, tt.wantHashrate)
+			}
+			if stats.Accepted != tt.wantAccepted || stats.Rejected != tt.wantRejected {
+				t.Errorf("shares = %d/%d, want %d/%d", stats.Accepted, stats.Rejected, tt.wantAccepted, tt.wantRejected)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"xoon/miner"
 	"xoon/ui"
 	"xoon/utils"
 
@@ -19,6 +20,7 @@ func main() {
 
 	app = tview.NewApplication()
 	rootFlex = tview.NewFlex().SetDirection(tview.FlexRow)
+	ui.SetModalRoot(rootFlex)
 
 	// Check for existing wallet
 	partialPublicKey := utils.CheckExistingWallet()
@@ -39,7 +41,12 @@ func main() {
 	})
 
 	app.SetRoot(rootFlex, true).EnableMouse(true)
-	if err := app.Run(); err != nil {
+	err := app.Run()
+
+	// The app may also end without the quit keys, e.g. on Ctrl+C
+	miner.StopAll()
+
+	if err != nil {
 		utils.ClearGlobalKeys()
 		panic(err)
 	}
@@ -93,6 +100,7 @@ func showMainInterface(app *tview.Application) {
 	solXENNvidiaGPUUI := ui.CreateSolXENNvidiaGPUUI(app)
 	solXENAMDGPUUI := ui.CreateSolXENAMDGPUUI(app)
 	tokenharvestUI := ui.CreateTokenHarvestUI(app)
+	workersUI := ui.CreateUnmineableWorkersUI(app)
 	switchView := ui.CreateSwitchViewFunc(rightFlex, mainMenu)
 
 	modules := []ui.ModuleUI{
@@ -121,6 +129,11 @@ func showMainInterface(app *tview.Application) {
 			ConfigFlex:    tokenharvestUI.ConfigFlex,
 			LogView:       tokenharvestUI.LogView,
 		},
+		{
+			DashboardFlex: workersUI.DashboardFlex,
+			ConfigFlex:    workersUI.ConfigFlex,
+			LogView:       workersUI.LogView,
+		},
 	}
 
 	ui.SetupMenuItemSelection(mainMenu, switchView, modules)
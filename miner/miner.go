@@ -0,0 +1,142 @@
+package miner
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"xoon/utils"
+
+	"github.com/rivo/tview"
+)
+
+// ErrAlreadyRunning is returned when starting a miner that is running
+var ErrAlreadyRunning = errors.New("miner is already running")
+
+// Config is what a miner is started with
+type Config struct {
+	// unMineable miner user, COIN:address.worker#referral
+	User      string
+	Algorithm string
+	Port      string
+	// CPU miners only
+	Threads string
+	// The watchdog restarts a miner without hashrate or accepted shares for
+	// this long, zero only restarts it when it exits
+	StallWindow time.Duration
+	// Restarts allowed per hour, zero turns the restarts off
+	MaxRestartsPerHour int
+	// Miners whose API can't be bound to localhost only start it when set,
+	// their stats are otherwise read from the output
+	OpenAPI bool
+}
+
+// Status is the state of the miner process
+type Status struct {
+	Running   bool
+	StartedAt time.Time
+	Config    Config
+	// Watchdog restarts since the miner was started by the user
+	Restarts          int
+	LastRestart       time.Time
+	LastRestartReason string
+}
+
+// Stats are the mining figures parsed from the miner output
+type Stats struct {
+	// Hashrate in H/s
+	Hashrate  float64
+	Accepted  int
+	Rejected  int
+	LastLine  string
+	UpdatedAt time.Time
+}
+
+// DeviceStats are the figures of a GPU reported by the API of a miner.
+// Readings the miner doesn't report are 0.
+type DeviceStats struct {
+	Index int
+	Name  string
+	// Hashrate in H/s
+	Hashrate float64
+	Accepted int
+	Rejected int
+	Stale    int
+	// Temperature in °C, fan speed in percent and power draw in watts
+	Temperature float64
+	FanSpeed    float64
+	Power       float64
+}
+
+// Efficiency is the hashrate per watt, 0 without a power reading
+func (d DeviceStats) Efficiency() float64 {
+	if d.Power <= 0 {
+		return 0
+	}
+	return d.Hashrate / d.Power
+}
+
+// Miner is a mining program the app installs, starts and watches
+type Miner interface {
+	Name() string
+	Install()
+	IsInstalled() bool
+	Start(config Config) error
+	Stop() error
+	Status() Status
+	Stats() Stats
+}
+
+// LineKind says how a line of miner output is shown in the log
+type LineKind int
+
+const (
+	// The line is logged
+	LineShow LineKind = iota
+	// The line is a periodic report, logged at most every report interval
+	LineReport
+	// The line is not logged
+	LineSkip
+)
+
+// Adapter is the part of a miner that differs between mining programs:
+// where it is installed, how it is started and what its output means.
+// NewSupervisor turns an adapter into a Miner.
+type Adapter interface {
+	Name() string
+	// Directory of the miner next to the executable
+	Dir() string
+	// Path of the miner program inside Dir
+	Executable() string
+	Algorithms() []string
+	Ports() []string
+	// Whether the miner takes a thread count
+	UsesThreads() bool
+	Args(config Config) []string
+	// ParseLine updates the stats from a line of output and says how to log it
+	ParseLine(line string, stats *Stats) LineKind
+	Install(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc)
+}
+
+// hashrateUnits are the multipliers of the hashrate units miners print
+var hashrateUnits = map[string]float64{
+	"":  1,
+	"k": 1e3,
+	"m": 1e6,
+	"g": 1e9,
+	"t": 1e12,
+}
+
+// ParseHashrate converts a printed hashrate like "12.5" "MH/s" to H/s
+func ParseHashrate(value string, unit string) (float64, bool) {
+	hashrate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	prefix := strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(unit, "H/s"), "h/s"))
+	multiplier, ok := hashrateUnits[prefix]
+	if !ok {
+		return 0, false
+	}
+	return hashrate * multiplier, true
+}
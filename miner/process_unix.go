@@ -0,0 +1,39 @@
+//go:build !windows
+
+package miner
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the miner in its own process group, so stopping
+// it reaches the processes it spawns and nothing else
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcess asks the process group of the miner to exit
+func terminateProcess(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGTERM)
+}
+
+// killProcess forces the process group of the miner to exit
+func killProcess(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// processRunning reports whether pid is alive and runs the executable
+func processRunning(pid int, executablePath string) bool {
+	// Signal 0 only checks that the process exists and is ours to signal
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	output, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "command=").Output()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(output), executablePath)
+}
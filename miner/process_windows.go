@@ -0,0 +1,36 @@
+//go:build windows
+
+package miner
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the miner in its own process group, so stopping
+// it reaches the processes it spawns and nothing else
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminateProcess asks the process tree of the miner to exit
+func terminateProcess(pid int) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// killProcess forces the process tree of the miner to exit
+func killProcess(pid int) error {
+	return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// processRunning reports whether pid is alive and runs the executable
+func processRunning(pid int, executablePath string) bool {
+	output, err := exec.Command("tasklist", "/FI", "PID eq "+strconv.Itoa(pid), "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(output)), strings.ToLower(filepath.Base(executablePath)))
+}
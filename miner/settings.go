@@ -0,0 +1,103 @@
+package miner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"xoon/utils"
+)
+
+const settingsFileName = "minerConfig.json"
+
+// Default watchdog settings
+const (
+	DefaultStallMinutes       = 10
+	DefaultMaxRestartsPerHour = 5
+)
+
+// Settings are the watchdog settings shared by all miners
+type Settings struct {
+	// Restart a miner without hashrate or accepted shares for this long, 0
+	// only restarts it when it exits
+	StallMinutes int `json:"stallMinutes"`
+	// 0 turns the restarts off
+	MaxRestartsPerHour int `json:"maxRestartsPerHour"`
+	// Start the SRBMiner API, which listens on every network interface. Off
+	// unless the user turns it on.
+	SRBMinerAPI bool `json:"srbMinerAPI"`
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		StallMinutes:       DefaultStallMinutes,
+		MaxRestartsPerHour: DefaultMaxRestartsPerHour,
+	}
+}
+
+// StallWindow is the stall window of the watchdog, zero when it is off
+func (s Settings) StallWindow() time.Duration {
+	return time.Duration(s.StallMinutes) * time.Minute
+}
+
+// ReadSettings returns the saved watchdog settings, the defaults if none are saved
+func ReadSettings() (Settings, error) {
+	file, err := os.ReadFile(filepath.Join(utils.GetExecutablePath(), settingsFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultSettings(), nil
+		}
+		return Settings{}, err
+	}
+
+	// Settings missing from the file keep their default
+	settings := defaultSettings()
+	if err := json.Unmarshal(file, &settings); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// WriteSettings validates the watchdog settings and saves them
+func WriteSettings(settings Settings) error {
+	if settings.StallMinutes < 0 || settings.MaxRestartsPerHour < 0 {
+		return fmt.Errorf("watchdog settings can't be negative")
+	}
+
+	file, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(utils.GetExecutablePath(), settingsFileName), file, 0644)
+}
+
+// GetSettings returns the saved watchdog settings, the defaults if they can't be read
+func GetSettings() Settings {
+	settings, err := ReadSettings()
+	if err != nil {
+		utils.LogToFile(fmt.Sprintf("Failed to read miner config, using the default watchdog settings: %v", err))
+		return defaultSettings()
+	}
+	return settings
+}
+
+// SetSRBMinerAPI saves whether the SRBMiner API is started with the miner
+func SetSRBMinerAPI(enabled bool) error {
+	settings, err := ReadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to read miner config: %v", err)
+	}
+	settings.SRBMinerAPI = enabled
+	return WriteSettings(settings)
+}
+
+// SetStallMinutes saves the stall window of the watchdog, 0 turns it off
+func SetStallMinutes(minutes int) error {
+	settings, err := ReadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to read miner config: %v", err)
+	}
+	settings.StallMinutes = minutes
+	return WriteSettings(settings)
+}
@@ -0,0 +1,373 @@
+package miner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"xoon/utils"
+
+	"github.com/rivo/tview"
+)
+
+// Report lines of the miner output are logged at most this often
+const reportInterval = 10 * time.Second
+
+// A miner that ignores the request to exit is killed after this long
+const stopTimeout = 10 * time.Second
+
+// The PID of the running miner is kept in its directory, so a miner left
+// behind by a crashed app is found on the next start
+const pidFileName = "miner.pid"
+
+// Supervisor runs the program of an adapter and keeps its status and stats
+type Supervisor struct {
+	adapter    Adapter
+	app        *tview.Application
+	logView    *tview.TextView
+	logMessage utils.LogMessageFunc
+
+	mutex      sync.Mutex
+	status     Status
+	stats      Stats
+	lastReport time.Time
+	watchdog   watchdog
+	// The process we started, nil when none, and closed when it exits
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+var _ Miner = (*Supervisor)(nil)
+
+var (
+	supervisors      []*Supervisor
+	supervisorsMutex sync.Mutex
+)
+
+// NewSupervisor creates a Miner for the adapter that logs to logView. A
+// miner process left behind by a previous run is stopped.
+func NewSupervisor(app *tview.Application, adapter Adapter, logView *tview.TextView, logMessage utils.LogMessageFunc) *Supervisor {
+	s := &Supervisor{
+		adapter:    adapter,
+		app:        app,
+		logView:    logView,
+		logMessage: logMessage,
+	}
+	s.stopOrphan()
+
+	supervisorsMutex.Lock()
+	supervisors = append(supervisors, s)
+	supervisorsMutex.Unlock()
+
+	return s
+}
+
+// StopAll stops the miners of all supervisors at once and waits for them,
+// for when the app exits. Supervisors whose miner isn't running are
+// stopped too, which drops their pending restarts.
+func StopAll() {
+	supervisorsMutex.Lock()
+	all := append([]*Supervisor{}, supervisors...)
+	supervisorsMutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, s := range all {
+		wg.Add(1)
+		go func(s *Supervisor) {
+			defer wg.Done()
+			if err := s.Stop(); err != nil {
+				utils.LogToFile(fmt.Sprintf("Error stopping %s: %v", s.Name(), err))
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+func (s *Supervisor) Name() string {
+	return s.adapter.Name()
+}
+
+// Adapter returns the miner specific part, for building its form
+func (s *Supervisor) Adapter() Adapter {
+	return s.adapter
+}
+
+func (s *Supervisor) Install() {
+	s.adapter.Install(s.app, s.logView, s.logMessage)
+}
+
+func (s *Supervisor) IsInstalled() bool {
+	_, err := os.Stat(s.executablePath())
+	return err == nil
+}
+
+func (s *Supervisor) dir() string {
+	return filepath.Join(utils.GetExecutablePath(), s.adapter.Dir())
+}
+
+func (s *Supervisor) executablePath() string {
+	return filepath.Join(s.dir(), s.adapter.Executable())
+}
+
+// Start launches the miner program with the config and watches it
+func (s *Supervisor) Start(config Config) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.status.Running {
+		return ErrAlreadyRunning
+	}
+	s.watchdog.reset(config)
+	if err := s.start(config); err != nil {
+		return err
+	}
+	s.status.Restarts = 0
+	s.status.LastRestart = time.Time{}
+	s.status.LastRestartReason = ""
+	return nil
+}
+
+// start launches the miner program, the mutex is held
+func (s *Supervisor) start(config Config) error {
+	if !s.IsInstalled() {
+		return fmt.Errorf("%s is not installed", s.adapter.Name())
+	}
+
+	// The miner runs in its directory, the working directory of the app
+	// is shared by all miners and left alone
+	cmd := exec.Command(s.executablePath(), s.adapter.Args(config)...)
+	cmd.Dir = s.dir()
+	setProcessGroup(cmd)
+
+	// Create pipes for both stdout and stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("error creating StdoutPipe: %v", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("error creating StderrPipe: %v", err)
+	}
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting miner: %v", err)
+	}
+
+	s.status.Running = true
+	s.status.StartedAt = time.Now()
+	s.status.Config = config
+	s.stats = Stats{}
+	s.lastReport = time.Time{}
+	s.watchdog.lastHashrate = s.status.StartedAt
+	s.watchdog.lastAccepted = s.status.StartedAt
+	s.cmd = cmd
+	s.done = make(chan struct{})
+
+	if err := s.writePIDFile(cmd.Process.Pid); err != nil {
+		utils.LogToFile(fmt.Sprintf("Failed to write %s PID file: %v", s.adapter.Name(), err))
+	}
+
+	s.logMessage(s.logView, fmt.Sprintf("%s started, initiating takes a while...", s.adapter.Name()))
+
+	// The pipes must be read to the end before cmd.Wait closes them
+	pipes := &sync.WaitGroup{}
+	pipes.Add(2)
+	go s.readPipe(stdout, pipes)
+	go s.readPipe(stderr, pipes)
+	go s.wait(cmd, pipes, s.done)
+	if s.watchdog.stallWindow > 0 {
+		go s.monitor(cmd, s.done)
+	}
+
+	return nil
+}
+
+// Stop asks the miner process we started to exit and kills it if it
+// hasn't after stopTimeout, and drops a pending restart. No other process
+// is touched.
+func (s *Supervisor) Stop() error {
+	s.mutex.Lock()
+	cmd, done := s.cmd, s.done
+	s.status.Running = false
+	restartPending := s.watchdog.cancel()
+	s.mutex.Unlock()
+
+	if cmd == nil && !restartPending {
+		return nil
+	}
+	if cmd != nil {
+		if err := stopProcess(cmd.Process.Pid, done); err != nil {
+			s.logMessage(s.logView, fmt.Sprintf("Error stopping %s: %v", s.adapter.Name(), err))
+		}
+	}
+	s.logMessage(s.logView, "Mining stopped")
+	return nil
+}
+
+// stopProcess terminates the process group of pid, then kills it if done
+// isn't closed within stopTimeout
+func stopProcess(pid int, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	default:
+	}
+
+	if err := terminateProcess(pid); err != nil {
+		utils.LogToFile(fmt.Sprintf("Failed to terminate process %d, killing it: %v", pid, err))
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopTimeout):
+	}
+
+	if err := killProcess(pid); err != nil {
+		return fmt.Errorf("failed to kill process %d: %v", pid, err)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopTimeout):
+		return fmt.Errorf("process %d did not exit", pid)
+	}
+}
+
+func (s *Supervisor) Status() Status {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.status
+}
+
+func (s *Supervisor) Stats() Stats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stats
+}
+
+// wait logs how the miner program ended and forgets the process, once its
+// output has been read. An exit that wasn't asked for by Stop or the
+// watchdog is restarted.
+func (s *Supervisor) wait(cmd *exec.Cmd, pipes *sync.WaitGroup, done chan struct{}) {
+	pipes.Wait()
+	err := cmd.Wait()
+
+	s.mutex.Lock()
+	unexpected := false
+	if s.cmd == cmd {
+		s.cmd = nil
+		s.removePIDFile()
+		unexpected = s.status.Running
+		s.status.Running = false
+	}
+	s.mutex.Unlock()
+	close(done)
+
+	if err != nil {
+		s.logMessage(s.logView, "Miner exited with error: "+err.Error())
+	} else {
+		s.logMessage(s.logView, "Mining completed successfully")
+	}
+
+	if unexpected {
+		reason := "exited"
+		if err != nil {
+			reason = "exited with error: " + err.Error()
+		}
+		s.scheduleRestart(reason)
+	}
+}
+
+func (s *Supervisor) pidFilePath() string {
+	return filepath.Join(s.dir(), pidFileName)
+}
+
+func (s *Supervisor) writePIDFile(pid int) error {
+	return os.WriteFile(s.pidFilePath(), []byte(strconv.Itoa(pid)), 0644)
+}
+
+func (s *Supervisor) removePIDFile() {
+	if err := os.Remove(s.pidFilePath()); err != nil && !os.IsNotExist(err) {
+		utils.LogToFile(fmt.Sprintf("Failed to remove %s PID file: %v", s.adapter.Name(), err))
+	}
+}
+
+// stopOrphan stops the miner process of the PID file if it still runs our
+// miner program. The PID may since belong to an unrelated process, which
+// is left alone.
+func (s *Supervisor) stopOrphan() {
+	content, err := os.ReadFile(s.pidFilePath())
+	if err != nil {
+		return
+	}
+	defer s.removePIDFile()
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 || !processRunning(pid, s.executablePath()) {
+		return
+	}
+
+	s.logMessage(s.logView, fmt.Sprintf("Stopping %s process %d left by a previous run", s.adapter.Name(), pid))
+	// Not our child, so its exit can't be waited for
+	if err := terminateProcess(pid); err != nil {
+		utils.LogToFile(fmt.Sprintf("Failed to terminate orphaned process %d: %v", pid, err))
+	}
+	deadline := time.Now().Add(stopTimeout)
+	for processRunning(pid, s.executablePath()) {
+		if time.Now().After(deadline) {
+			if err := killProcess(pid); err != nil {
+				s.logMessage(s.logView, fmt.Sprintf("Error killing process %d: %v", pid, err))
+			}
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// readPipe parses the miner output and logs the lines the adapter wants
+// shown. It marks pipes done when the output ends.
+func (s *Supervisor) readPipe(pipe io.Reader, pipes *sync.WaitGroup) {
+	defer pipes.Done()
+
+	scanner := bufio.NewScanner(pipe)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		s.mutex.Lock()
+		accepted := s.stats.Accepted
+		kind := s.adapter.ParseLine(line, &s.stats)
+		s.stats.LastLine = line
+		s.stats.UpdatedAt = time.Now()
+		if s.stats.Hashrate > 0 {
+			s.watchdog.lastHashrate = s.stats.UpdatedAt
+		}
+		if s.stats.Accepted > accepted {
+			s.watchdog.lastAccepted = s.stats.UpdatedAt
+		}
+		if kind == LineReport {
+			if time.Since(s.lastReport) < reportInterval {
+				kind = LineSkip
+			} else {
+				s.lastReport = time.Now()
+			}
+		}
+		s.mutex.Unlock()
+
+		if kind != LineSkip {
+			s.logMessage(s.logView, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.logMessage(s.logView, fmt.Sprintf("Error reading pipe: %v", err))
+	}
+}
@@ -0,0 +1,185 @@
+package miner
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+	"xoon/utils"
+)
+
+const (
+	// The first restart waits this long, each following one twice as long
+	initialRestartDelay = 5 * time.Second
+	maxRestartDelay     = 5 * time.Minute
+	// A miner that ran this long before failing restarts without the
+	// delay built up by earlier failures
+	restartDelayReset = 15 * time.Minute
+	// How often a running miner is checked for stalling
+	watchdogInterval = 30 * time.Second
+)
+
+// watchdog is the restart state of a supervisor, guarded by its mutex
+type watchdog struct {
+	// Restart when no hashrate or accepted share was seen for this long,
+	// zero only restarts on exit
+	stallWindow time.Duration
+	maxRestarts int
+	delay       time.Duration
+	// Restarts within the last hour
+	restarts []time.Time
+	// The pending restart, nil when none
+	timer *time.Timer
+	// When the output last showed a hashrate and a new accepted share
+	lastHashrate time.Time
+	lastAccepted time.Time
+}
+
+// reset forgets earlier restarts and uses the settings of config
+func (w *watchdog) reset(config Config) {
+	w.cancel()
+	w.stallWindow = config.StallWindow
+	w.maxRestarts = config.MaxRestartsPerHour
+	w.delay = 0
+	w.restarts = nil
+}
+
+// cancel drops the pending restart and reports whether there was one
+func (w *watchdog) cancel() bool {
+	if w.timer == nil {
+		return false
+	}
+	w.timer.Stop()
+	w.timer = nil
+	return true
+}
+
+// next records a restart at now of a miner started at startedAt and
+// returns how long to wait before it, false when the miner was restarted
+// maxRestarts times within the last hour
+func (w *watchdog) next(now time.Time, startedAt time.Time) (time.Duration, bool) {
+	// 1. Limit the restarts per hour
+	recent := w.restarts[:0]
+	for _, restart := range w.restarts {
+		if now.Sub(restart) < time.Hour {
+			recent = append(recent, restart)
+		}
+	}
+	w.restarts = recent
+	if len(w.restarts) >= w.maxRestarts {
+		return 0, false
+	}
+
+	// 2. Back off exponentially, from the start again after a long run
+	if w.delay == 0 || now.Sub(startedAt) >= restartDelayReset {
+		w.delay = initialRestartDelay
+	}
+	delay := w.delay
+	w.delay = min(w.delay*2, maxRestartDelay)
+	w.restarts = append(w.restarts, now)
+	return delay, true
+}
+
+// scheduleRestart starts the miner again with its last config after the
+// restart delay, unless it was restarted too often in the last hour
+func (s *Supervisor) scheduleRestart(reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	w := &s.watchdog
+	if s.status.Running || w.timer != nil {
+		return
+	}
+
+	// 1. Limit the restarts per hour and back off
+	now := time.Now()
+	delay, ok := w.next(now, s.status.StartedAt)
+	if !ok {
+		message := fmt.Sprintf("%s %s, not restarting after %d restarts in the last hour", s.adapter.Name(), reason, len(w.restarts))
+		utils.LogToFile(message)
+		s.logMessage(s.logView, message)
+		return
+	}
+
+	s.status.Restarts++
+	s.status.LastRestart = now
+	s.status.LastRestartReason = reason
+
+	message := fmt.Sprintf("%s %s, restarting in %s (%d/%d this hour)", s.adapter.Name(), reason, delay, len(w.restarts), w.maxRestarts)
+	utils.LogToFile(message)
+	s.logMessage(s.logView, message)
+
+	// 2. Start the miner when the delay is over, unless Start or Stop came first
+	config := s.status.Config
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		s.mutex.Lock()
+		if w.timer != timer {
+			s.mutex.Unlock()
+			return
+		}
+		w.timer = nil
+		err := s.start(config)
+		s.mutex.Unlock()
+
+		if err != nil {
+			s.logMessage(s.logView, fmt.Sprintf("Error restarting %s: %v", s.adapter.Name(), err))
+			s.scheduleRestart("failed to restart")
+		}
+	})
+	w.timer = timer
+}
+
+// monitor restarts the miner of cmd when it stalls, until it exits
+func (s *Supervisor) monitor(cmd *exec.Cmd, done <-chan struct{}) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		reason := s.stallReason(cmd)
+		if reason == "" {
+			continue
+		}
+
+		// Stopped like by the user, so wait doesn't count it as a crash
+		s.mutex.Lock()
+		if s.cmd != cmd || !s.status.Running {
+			s.mutex.Unlock()
+			return
+		}
+		s.status.Running = false
+		s.mutex.Unlock()
+
+		if err := stopProcess(cmd.Process.Pid, done); err != nil {
+			s.logMessage(s.logView, fmt.Sprintf("Error stopping %s: %v", s.adapter.Name(), err))
+		}
+		s.scheduleRestart(reason)
+		return
+	}
+}
+
+// stallReason says why the miner of cmd counts as stalled, empty if it doesn't
+func (s *Supervisor) stallReason(cmd *exec.Cmd) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	w := &s.watchdog
+	if s.cmd != cmd || !s.status.Running || w.stallWindow <= 0 {
+		return ""
+	}
+
+	now := time.Now()
+	switch {
+	case now.Sub(w.lastHashrate) >= w.stallWindow:
+		return fmt.Sprintf("reported no hashrate for %s", w.stallWindow)
+	case now.Sub(w.lastAccepted) >= w.stallWindow:
+		return fmt.Sprintf("had no accepted shares for %s", w.stallWindow)
+	default:
+		return ""
+	}
+}
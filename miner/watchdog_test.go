@@ -0,0 +1,149 @@
+package miner
+
+import (
+	"testing"
+	"time"
+	"xoon/utils"
+
+	"github.com/rivo/tview"
+)
+
+func TestWatchdogNext(t *testing.T) {
+	type restart struct {
+		// When the miner failed and how long it had run, from the start of the test
+		at        time.Duration
+		ran       time.Duration
+		wantDelay time.Duration
+		wantOK    bool
+	}
+
+	tests := []struct {
+		name        string
+		maxRestarts int
+		restarts    []restart
+	}{
+		{
+			name:        "backoff doubles up to the maximum",
+			maxRestarts: 10,
+			restarts: []restart{
+				{0, time.Second, 5 * time.Second, true},
+				{time.Minute, time.Second, 10 * time.Second, true},
+				{2 * time.Minute, time.Second, 20 * time.Second, true},
+				{3 * time.Minute, time.Second, 40 * time.Second, true},
+				{4 * time.Minute, time.Second, 80 * time.Second, true},
+				{5 * time.Minute, time.Second, 160 * time.Second, true},
+				{6 * time.Minute, time.Second, 5 * time.Minute, true},
+				{7 * time.Minute, time.Second, 5 * time.Minute, true},
+			},
+		},
+		{
+			name:        "long run starts the backoff again",
+			maxRestarts: 10,
+			restarts: []restart{
+				{0, time.Second, 5 * time.Second, true},
+				{time.Minute, time.Second, 10 * time.Second, true},
+				{30 * time.Minute, restartDelayReset, 5 * time.Second, true},
+				{31 * time.Minute, time.Second, 10 * time.Second, true},
+			},
+		},
+		{
+			name:        "hourly cap",
+			maxRestarts: 3,
+			restarts: []restart{
+				{0, time.Second, 5 * time.Second, true},
+				{time.Minute, time.Second, 10 * time.Second, true},
+				{2 * time.Minute, time.Second, 20 * time.Second, true},
+				{3 * time.Minute, time.Second, 0, false},
+				{59 * time.Minute, time.Second, 0, false},
+				// The first restart is an hour old
+				{time.Hour, time.Second, 40 * time.Second, true},
+				{time.Hour + time.Minute, time.Second, 80 * time.Second, true},
+				{time.Hour + 90*time.Second, time.Second, 0, false},
+			},
+		},
+		{
+			name:        "restarts off",
+			maxRestarts: 0,
+			restarts: []restart{
+				{0, time.Second, 0, false},
+			},
+		},
+	}
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w watchdog
+			w.reset(Config{MaxRestartsPerHour: tt.maxRestarts})
+
+			for i, r := range tt.restarts {
+				now := start.Add(r.at)
+				delay, ok := w.next(now, now.Add(-r.ran))
+				if delay != r.wantDelay || ok != r.wantOK {
+					t.Fatalf("restart %d: next = %s, %v, want %s, %v", i, delay, ok, r.wantDelay, r.wantOK)
+				}
+			}
+		})
+	}
+}
+
+// testAdapter is an adapter for a miner that isn't installed
+type testAdapter struct{}
+
+func (testAdapter) Name() string                                 { return "Test miner" }
+func (testAdapter) Dir() string                                  { return "test-miner-not-installed" }
+func (testAdapter) Executable() string                           { return "miner" }
+func (testAdapter) Algorithms() []string                         { return nil }
+func (testAdapter) Ports() []string                              { return nil }
+func (testAdapter) UsesThreads() bool                            { return false }
+func (testAdapter) Args(config Config) []string                  { return nil }
+func (testAdapter) ParseLine(line string, stats *Stats) LineKind { return LineShow }
+func (testAdapter) Install(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc) {
+}
+
+func newTestSupervisor() *Supervisor {
+	s := NewSupervisor(nil, testAdapter{}, nil, func(*tview.TextView, string) {})
+	s.watchdog.reset(Config{MaxRestartsPerHour: DefaultMaxRestartsPerHour})
+	return s
+}
+
+func restartPending(s *Supervisor) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.watchdog.timer != nil
+}
+
+func TestStopDropsPendingRestart(t *testing.T) {
+	s := newTestSupervisor()
+	s.scheduleRestart("exited")
+
+	status := s.Status()
+	if status.Restarts != 1 || status.LastRestartReason != "exited" || status.LastRestart.IsZero() {
+		t.Fatalf("status = %+v, want one restart for exited", status)
+	}
+	if !restartPending(s) {
+		t.Fatal("no restart pending")
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if restartPending(s) {
+		t.Fatal("restart still pending after Stop")
+	}
+}
+
+func TestStopAllDropsPendingRestarts(t *testing.T) {
+	pending := newTestSupervisor()
+	pending.scheduleRestart("had no accepted shares for 10m0s")
+	idle := newTestSupervisor()
+
+	StopAll()
+
+	if restartPending(pending) {
+		t.Fatal("restart still pending after StopAll")
+	}
+	if idle.Status().Running {
+		t.Fatal("idle miner runs")
+	}
+}
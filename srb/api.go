@@ -0,0 +1,170 @@
+package xenblocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"xoon/miner"
+)
+
+// The SRBMiner API is polled on localhost. SRBMiner has no option to bind
+// the API to an address, so it listens on API_PORT of every interface. It
+// is only started when the user turns it on, see miner.Config.OpenAPI.
+// Restricted mode turns off the API commands that control the miner, so
+// other hosts can at most read the stats.
+const (
+	API_HOST = "127.0.0.1"
+	API_PORT = 18090
+)
+
+// apiArgs enables the HTTP API on the SRBMiner command line, see API_PORT
+// for who can reach it
+func apiArgs() []string {
+	return []string{
+		"--api-enable",
+		"--api-port", strconv.Itoa(API_PORT),
+		"--api-rig-restricted",
+	}
+}
+
+// apiShares are share counts, for all devices or one
+type apiShares struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+	Stale    int `json:"stale"`
+}
+
+// apiAlgorithm is a mined algorithm. Per device figures are keyed by
+// "gpu" and the device id, hashrates usually also have the "total".
+type apiAlgorithm struct {
+	Name string `json:"name"`
+	Pool struct {
+		Pool    string `json:"pool"`
+		Latency int64  `json:"latency"`
+	} `json:"pool"`
+	Hashrate struct {
+		GPU map[string]float64 `json:"gpu"`
+	} `json:"hashrate"`
+	Shares struct {
+		apiShares
+		GPU map[string]apiShares `json:"gpu"`
+	} `json:"shares"`
+}
+
+// apiDevice is the telemetry of a GPU
+type apiDevice struct {
+	ID          int     `json:"id"`
+	Model       string  `json:"model"`
+	Temperature float64 `json:"temperature"`
+	FanSpeed    float64 `json:"fan_speed"`
+	Power       float64 `json:"power"`
+}
+
+// apiSummary is the part of the SRBMiner /stats answer shown in the app
+type apiSummary struct {
+	MinerVersion string         `json:"miner_version"`
+	MiningTime   int64          `json:"mining_time"`
+	Algorithms   []apiAlgorithm `json:"algorithms"`
+	GPUDevices   []apiDevice    `json:"gpu_devices"`
+}
+
+// APIStats are the mining figures reported by the SRBMiner API
+type APIStats struct {
+	Version     string
+	Algorithm   string
+	Pool        string
+	PoolLatency time.Duration
+	Uptime      time.Duration
+	// Total hashrate in H/s
+	Hashrate float64
+	Accepted int
+	Rejected int
+	Stale    int
+	GPUs     []miner.DeviceStats
+}
+
+// APIClient reads the stats of the running SRBMiner from its API
+type APIClient struct {
+	BaseURL string
+	client  *http.Client
+}
+
+// NewAPIClient creates a client for the API of the SRBMiner started by the app
+func NewAPIClient() *APIClient {
+	return &APIClient{
+		BaseURL: fmt.Sprintf("http://%s:%d", API_HOST, API_PORT),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// GetStats polls /stats for the totals and the figures of every GPU
+func (c *APIClient) GetStats() (*APIStats, error) {
+	resp, err := c.client.Get(c.BaseURL + "/stats")
+	if err != nil {
+		return nil, fmt.Errorf("SRBMiner API unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SRBMiner API returned %s", resp.Status)
+	}
+	var summary apiSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("error decoding SRBMiner API: %v", err)
+	}
+
+	stats := &APIStats{
+		Version: summary.MinerVersion,
+		Uptime:  time.Duration(summary.MiningTime) * time.Second,
+	}
+
+	// 1. Totals of the mined algorithm, the CPU is disabled
+	var algorithm apiAlgorithm
+	if len(summary.Algorithms) > 0 {
+		algorithm = summary.Algorithms[0]
+		stats.Algorithm = algorithm.Name
+		stats.Pool = algorithm.Pool.Pool
+		stats.PoolLatency = time.Duration(algorithm.Pool.Latency) * time.Millisecond
+		stats.Hashrate = algorithm.Hashrate.GPU["total"]
+		stats.Accepted = algorithm.Shares.Accepted
+		stats.Rejected = algorithm.Shares.Rejected
+		stats.Stale = algorithm.Shares.Stale
+	}
+
+	// 2. Telemetry, hashrate and shares of every GPU
+	for _, device := range summary.GPUDevices {
+		key := deviceKey(device.ID)
+		shares := algorithm.Shares.GPU[key]
+		stats.GPUs = append(stats.GPUs, miner.DeviceStats{
+			Index:       device.ID,
+			Name:        device.Model,
+			Hashrate:    algorithm.Hashrate.GPU[key],
+			Accepted:    shares.Accepted,
+			Rejected:    shares.Rejected,
+			Stale:       shares.Stale,
+			Temperature: device.Temperature,
+			FanSpeed:    device.FanSpeed,
+			Power:       device.Power,
+		})
+	}
+	sort.SliceStable(stats.GPUs, func(i, j int) bool {
+		return stats.GPUs[i].Index < stats.GPUs[j].Index
+	})
+
+	// 3. Without a total the hashrate is the sum of the GPUs
+	if _, ok := algorithm.Hashrate.GPU["total"]; !ok {
+		for _, gpu := range stats.GPUs {
+			stats.Hashrate += gpu.Hashrate
+		}
+	}
+
+	return stats, nil
+}
+
+// deviceKey is the key of a device in the per device figures
+func deviceKey(id int) string {
+	return "gpu" + strconv.Itoa(id)
+}
@@ -0,0 +1,285 @@
+package xenblocks
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"xoon/miner"
+)
+
+// /stats of SRBMiner-MULTI 2.6.6 mining Blake3 on two AMD GPUs, the CPU disabled
+const testStatsJSON = `{
+  "rig_name": "",
+  "miner_version": "2.6.6",
+  "mining_time": 7384,
+  "total_cpu_workers": 0,
+  "total_gpu_workers": 2,
+  "driver_version": "23.40.2",
+  "compute_version": "",
+  "cpu_devices": [],
+  "gpu_devices": [
+    {
+      "id": 0,
+      "bus_id": 3,
+      "device": "gpu0",
+      "model": "AMD Radeon RX 6800",
+      "vendor": "amd",
+      "memory": 16368,
+      "temperature": 63,
+      "temperature_memory": 76,
+      "temperature_hotspot": 81,
+      "fan_speed": 48,
+      "fan_speed_rpm": 1712,
+      "power": 156,
+      "core_clock": 2105,
+      "memory_clock": 1000
+    },
+    {
+      "id": 1,
+      "bus_id": 6,
+      "device": "gpu1",
+      "model": "AMD Radeon RX 6600",
+      "vendor": "amd",
+      "memory": 8176,
+      "temperature": 71,
+      "temperature_memory": 82,
+      "temperature_hotspot": 88,
+      "fan_speed": 62,
+      "fan_speed_rpm": 2204,
+      "power": 98,
+      "core_clock": 2491,
+      "memory_clock": 875
+    }
+  ],
+  "algorithms": [
+    {
+      "id": 0,
+      "name": "blake3_alephium",
+      "pool": {
+        "pool": "stratum+ssl://blake3.unmineable.com:4444",
+        "worker": "SOL:9wFFyRfZBsuAha4YcuxcXLKwMxJR43S7fPfQLusDBzvT.xoon#plxp-imd8",
+        "uptime": 7370,
+        "latency": 41,
+        "difficulty": "4.29 G"
+      },
+      "hashrate": {
+        "now": 2987125000.5,
+        "cpu": {"total": 0},
+        "gpu": {"total": 2987125000.5, "gpu0": 1894221000.25, "gpu1": 1092904000.25}
+      },
+      "shares": {
+        "accepted": 213,
+        "rejected": 2,
+        "stale": 1,
+        "cpu": {},
+        "gpu": {
+          "gpu0": {"accepted": 135, "rejected": 1, "stale": 1},
+          "gpu1": {"accepted": 78, "rejected": 1, "stale": 0}
+        }
+      }
+    }
+  ]
+}`
+
+// fakeAPIServer serves a /stats body like SRBMiner and can be told to fail requests
+type fakeAPIServer struct {
+	server *httptest.Server
+
+	mutex sync.Mutex
+	body  string
+	// The next requests answered with 500
+	serverErrorNext int
+	requests        int
+}
+
+func newFakeAPIServer(t *testing.T, body string) *fakeAPIServer {
+	fake := &fakeAPIServer{body: body}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (f *fakeAPIServer) client() *APIClient {
+	return &APIClient{BaseURL: f.server.URL, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// FailNext answers the next n requests with 500 Internal Server Error
+func (f *fakeAPIServer) FailNext(n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.serverErrorNext = n
+}
+
+func (f *fakeAPIServer) Requests() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.requests
+}
+
+func (f *fakeAPIServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.requests++
+
+	if f.serverErrorNext > 0 {
+		f.serverErrorNext--
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Path != "/stats" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(f.body))
+}
+
+func TestGetStats(t *testing.T) {
+	stats, err := newFakeAPIServer(t, testStatsJSON).client().GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+
+	if stats.Version != "2.6.6" || stats.Algorithm != "blake3_alephium" || stats.Uptime != 7384*time.Second {
+		t.Errorf("version %q, algorithm %q, uptime %s", stats.Version, stats.Algorithm, stats.Uptime)
+	}
+	if stats.Pool != "stratum+ssl://blake3.unmineable.com:4444" || stats.PoolLatency != 41*time.Millisecond {
+		t.Errorf("pool %q, latency %s", stats.Pool, stats.PoolLatency)
+	}
+	if stats.Hashrate != 2987125000.5 {
+		t.Errorf("hashrate %v", stats.Hashrate)
+	}
+	if stats.Accepted != 213 || stats.Rejected != 2 || stats.Stale != 1 {
+		t.Errorf("shares %d/%d/%d", stats.Accepted, stats.Rejected, stats.Stale)
+	}
+
+	want := []miner.DeviceStats{
+		{Index: 0, Name: "AMD Radeon RX 6800", Hashrate: 1894221000.25, Accepted: 135, Rejected: 1, Stale: 1, Temperature: 63, FanSpeed: 48, Power: 156},
+		{Index: 1, Name: "AMD Radeon RX 6600", Hashrate: 1092904000.25, Accepted: 78, Rejected: 1, Stale: 0, Temperature: 71, FanSpeed: 62, Power: 98},
+	}
+	checkGPUs(t, stats.GPUs, want)
+}
+
+func TestGetStatsDevices(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantHashrate float64
+		want         []miner.DeviceStats
+	}{
+		{
+			// GPU 1 is disabled, the figures of GPU 2 are keyed by its id
+			name: "per GPU keys follow the device id",
+			body: `{
+  "miner_version": "2.6.6",
+  "gpu_devices": [
+    {"id": 0, "model": "AMD Radeon RX 6800", "temperature": 63},
+    {"id": 2, "model": "AMD Radeon RX 7900 XTX", "temperature": 58}
+  ],
+  "algorithms": [{
+    "name": "karlsenhashv2",
+    "hashrate": {"gpu": {"total": 3000, "gpu0": 1000, "gpu2": 2000}},
+    "shares": {"accepted": 9, "gpu": {"gpu0": {"accepted": 4}, "gpu2": {"accepted": 5, "stale": 1}}}
+  }]
+}`,
+			wantHashrate: 3000,
+			want: []miner.DeviceStats{
+				{Index: 0, Name: "AMD Radeon RX 6800", Hashrate: 1000, Accepted: 4, Temperature: 63},
+				{Index: 2, Name: "AMD Radeon RX 7900 XTX", Hashrate: 2000, Accepted: 5, Stale: 1, Temperature: 58},
+			},
+		},
+		{
+			name: "missing total is the sum of the GPUs",
+			body: `{
+  "miner_version": "2.6.6",
+  "gpu_devices": [{"id": 0, "model": "AMD Radeon RX 6800"}, {"id": 1, "model": "AMD Radeon RX 6600"}],
+  "algorithms": [{"name": "fishhash", "hashrate": {"gpu": {"gpu0": 1500, "gpu1": 700}}, "shares": {}}]
+}`,
+			wantHashrate: 2200,
+			want: []miner.DeviceStats{
+				{Index: 0, Name: "AMD Radeon RX 6800", Hashrate: 1500},
+				{Index: 1, Name: "AMD Radeon RX 6600", Hashrate: 700},
+			},
+		},
+		{
+			name: "GPUs sorted by index",
+			body: `{
+  "miner_version": "2.6.6",
+  "gpu_devices": [
+    {"id": 3, "model": "AMD Radeon RX 6600"},
+    {"id": 0, "model": "AMD Radeon RX 6800"},
+    {"id": 1, "model": "AMD Radeon RX 6700 XT"}
+  ],
+  "algorithms": [{"name": "blake3_alephium", "hashrate": {"gpu": {"total": 6, "gpu0": 1, "gpu1": 2, "gpu3": 3}}, "shares": {}}]
+}`,
+			wantHashrate: 6,
+			want: []miner.DeviceStats{
+				{Index: 0, Name: "AMD Radeon RX 6800", Hashrate: 1},
+				{Index: 1, Name: "AMD Radeon RX 6700 XT", Hashrate: 2},
+				{Index: 3, Name: "AMD Radeon RX 6600", Hashrate: 3},
+			},
+		},
+		{
+			// Before the GPUs are set up SRBMiner has no algorithm figures
+			name: "no algorithms yet",
+			body: `{"miner_version": "2.6.6", "gpu_devices": [{"id": 0, "model": "AMD Radeon RX 6800", "temperature": 40}], "algorithms": []}`,
+			want: []miner.DeviceStats{
+				{Index: 0, Name: "AMD Radeon RX 6800", Temperature: 40},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := newFakeAPIServer(t, tt.body).client().GetStats()
+			if err != nil {
+				t.Fatalf("GetStats: %v", err)
+			}
+			if math.Abs(stats.Hashrate-tt.wantHashrate) > 1e-9 {
+				t.Errorf("hashrate %v, want %v", stats.Hashrate, tt.wantHashrate)
+			}
+			checkGPUs(t, stats.GPUs, tt.want)
+		})
+	}
+}
+
+func TestGetStatsServerError(t *testing.T) {
+	fake := newFakeAPIServer(t, testStatsJSON)
+	fake.FailNext(1)
+	client := fake.client()
+
+	// The client doesn't retry, the panel polls again
+	_, err := client.GetStats()
+	if err == nil || !strings.Contains(err.Error(), "500 Internal Server Error") {
+		t.Fatalf("error = %v, want a 500", err)
+	}
+	if _, err := client.GetStats(); err != nil {
+		t.Fatalf("GetStats after the failure: %v", err)
+	}
+	if fake.Requests() != 2 {
+		t.Fatalf("%d requests, want 2", fake.Requests())
+	}
+}
+
+func TestGetStatsInvalidJSON(t *testing.T) {
+	_, err := newFakeAPIServer(t, `{"miner_version": "2.6.6", "gpu_devices": {}`).client().GetStats()
+	if err == nil || !strings.Contains(err.Error(), "error decoding SRBMiner API") {
+		t.Fatalf("error = %v, want a decoding error", err)
+	}
+}
+
+func checkGPUs(t *testing.T, got []miner.DeviceStats, want []miner.DeviceStats) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%d GPUs %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GPU %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -1,191 +1,132 @@
 package xenblocks
 
 import (
-	"bufio"
-	"fmt"
-	"io"
-	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
-	"sync"
-	"time"
+	"xoon/miner"
 	"xoon/utils"
 
 	"github.com/rivo/tview"
 )
 
-var isMining bool = false
-
 // AMD GPU algorithms
 var GPUAlgorithms = []string{"FishHash (GPU>6GB)", "Blake3 (GPU>4GB)", "KarlsenHash (GPU>3GB)"}
 
 // Mining ports
 var GPUMiningPorts = []string{"4444", "443", "3333", "13333", "80"}
 
-func StartMining(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc,
-	publicKey string, selectedAlgorithm string, selectedPort string, workerName string) {
+var (
+	// Total: 1.23 MH/s
+	speedPattern = regexp.MustCompile(`(?i)total[^:]*:\s*([\d.]+)\s*(\w*h/s)`)
+	// GPU0 result accepted [12ms], one line per share
+	resultPattern = regexp.MustCompile(`(?i)\bresult\s+(accepted|rejected)\b`)
+	// Accepted: 12 and Rejected: 1 of the periodic summary, the totals so far
+	acceptedTotalPattern = regexp.MustCompile(`(?i)\baccepted:\s*(\d+)`)
+	rejectedTotalPattern = regexp.MustCompile(`(?i)\brejected:\s*(\d+)`)
+)
 
-	isMining = true
+// Adapter runs SRBMiner-MULTI on unMineable
+type Adapter struct{}
 
-	go func() {
-		var srbMinerPath = filepath.Join(utils.GetExecutablePath(), SRB_MINER_DIR)
+func (Adapter) Name() string {
+	return "SRBMiner"
+}
 
-		var err = os.Chdir(srbMinerPath)
-		if err != nil {
-			logMessage(logView, "Error changing to xenblocksMiner directory: "+err.Error())
-			return
-		}
+func (Adapter) Dir() string {
+	return SRB_MINER_DIR
+}
 
-		// Set algorithm and host based on selectedAlgorithm
-		var algorithm, host string
-		switch selectedAlgorithm {
-		case "KarlsenHash (GPU>3GB)":
-			algorithm = "karlsenhashv2"
-			host = "karlsenhash.unmineable.com"
-		case "Blake3 (GPU>4GB)":
-			algorithm = "blake3_alephium"
-			host = "blake3.unmineable.com"
-		case "FishHash (GPU>6GB)":
-			algorithm = "fishhash"
-			host = "fishhash.unmineable.com"
-		default:
-			algorithm = "blake3_alephium"
-			host = "blake3.unmineable.com"
-		}
+func (Adapter) Executable() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join("SRBMiner-Multi-2-6-6", "SRBMiner-MULTI.exe")
+	}
+	return filepath.Join("SRBMiner-Multi-2-6-6", "SRBMiner-MULTI")
+}
 
-		// Construct the mining address
-		miningAddress := host + ":" + selectedPort
-		if selectedPort == "443" || selectedPort == "4444" {
-			miningAddress = "stratum+ssl://" + miningAddress
-		}
+func (Adapter) Algorithms() []string {
+	return GPUAlgorithms
+}
 
-		// Construct the arguments slice
-		args := []string{
-			"--algorithm", algorithm,
-			"--disable-cpu",
-			"--pool", miningAddress,
-			"--wallet", fmt.Sprintf("SOL:%s.%s#plxp-imd8", publicKey, workerName),
-		}
+func (Adapter) Ports() []string {
+	return GPUMiningPorts
+}
 
-		var executableName string
-		if runtime.GOOS == "windows" {
-			executableName = ".\\SRBMiner-Multi-2-6-6\\SRBMiner-MULTI.exe"
-		} else {
-			executableName = "./SRBMiner-Multi-2-6-6/SRBMiner-MULTI"
-		}
+func (Adapter) UsesThreads() bool {
+	return false
+}
 
-		cmd := exec.Command(executableName, args...)
+func (Adapter) Args(config miner.Config) []string {
+	// Set algorithm and host based on the selected algorithm
+	var algorithm, host string
+	switch config.Algorithm {
+	case "KarlsenHash (GPU>3GB)":
+		algorithm = "karlsenhashv2"
+		host = "karlsenhash.unmineable.com"
+	case "Blake3 (GPU>4GB)":
+		algorithm = "blake3_alephium"
+		host = "blake3.unmineable.com"
+	case "FishHash (GPU>6GB)":
+		algorithm = "fishhash"
+		host = "fishhash.unmineable.com"
+	default:
+		algorithm = "blake3_alephium"
+		host = "blake3.unmineable.com"
+	}
 
-		// Create pipes for both stdout and stderr
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			logMessage(logView, "Error creating StdoutPipe: "+err.Error())
-			return
-		}
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			logMessage(logView, "Error creating StderrPipe: "+err.Error())
-			return
-		}
+	// Construct the mining address
+	miningAddress := host + ":" + config.Port
+	if config.Port == "443" || config.Port == "4444" {
+		miningAddress = "stratum+ssl://" + miningAddress
+	}
 
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			logMessage(logView, "Error starting miner: "+err.Error())
-			return
-		}
+	args := []string{
+		"--algorithm", algorithm,
+		"--disable-cpu",
+		"--pool", miningAddress,
+		"--wallet", config.User,
+	}
+	// The API is open to the network, without it the stats come from the output
+	if config.OpenAPI {
+		args = append(args, apiArgs()...)
+	}
+	return args
+}
 
-		var (
-			lastUpdateTime time.Time
-			mutex          sync.Mutex
-		)
-
-		logMessage(logView, "Debug: Start Mining...Initiating takes a while...")
-
-		// Function to read from a pipe and send to UI
-		readPipe := func(pipe io.Reader) {
-			reader := bufio.NewReader(pipe)
-
-			for {
-				line, err := reader.ReadString('\n')
-				if err != nil {
-					if err == io.EOF {
-						time.Sleep(100 * time.Millisecond)
-						continue
-					}
-					app.QueueUpdateDraw(func() {
-						logMessage(logView, fmt.Sprintf("Error reading pipe: %v", err))
-					})
-					break
-				}
-
-				line = strings.TrimSpace(line)
-				if line != "" {
-					if strings.Contains(line, "Mining:") {
-						mutex.Lock()
-						now := time.Now()
-						if now.Sub(lastUpdateTime) >= 10*time.Second {
-							lastUpdateTime = now
-							app.QueueUpdateDraw(func() {
-								logMessage(logView, line)
-							})
-						}
-						mutex.Unlock()
-					} else if strings.Contains(line, "Ecosystem") {
-						// skip
-					} else {
-						app.QueueUpdateDraw(func() {
-							logMessage(logView, line)
-						})
-					}
-				}
-
-				// Force a UI update after each line
-				app.QueueUpdateDraw(func() {})
-			}
+func (Adapter) ParseLine(line string, stats *miner.Stats) miner.LineKind {
+	if match := speedPattern.FindStringSubmatch(line); match != nil {
+		if hashrate, ok := miner.ParseHashrate(match[1], match[2]); ok {
+			stats.Hashrate = hashrate
 		}
-
-		// Start goroutines to read from stdout and stderr
-		go readPipe(stdout)
-		go readPipe(stderr)
-
-		// Wait for the command to finish
-		if err := cmd.Wait(); err != nil {
-			app.QueueUpdateDraw(func() {
-				logMessage(logView, "Miner exited with error: "+err.Error())
-			})
+	}
+	// Shares are counted from the result lines, the summary totals replace
+	// the counts when they are printed
+	if match := resultPattern.FindStringSubmatch(line); match != nil {
+		if strings.EqualFold(match[1], "accepted") {
+			stats.Accepted++
 		} else {
-			app.QueueUpdateDraw(func() {
-				logMessage(logView, "Mining completed successfully")
-			})
+			stats.Rejected++
 		}
-	}()
-}
-
-func StopMining(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc) {
-	KillMiningProcess()
-	logMessage(logView, "Mining stopped")
-	isMining = false
-
-	// Change directory to parent
-	if err := os.Chdir(".."); err != nil {
-		logMessage(logView, "Error changing to parent directory: "+err.Error())
 	}
-}
+	if match := acceptedTotalPattern.FindStringSubmatch(line); match != nil {
+		stats.Accepted, _ = strconv.Atoi(match[1])
+	}
+	if match := rejectedTotalPattern.FindStringSubmatch(line); match != nil {
+		stats.Rejected, _ = strconv.Atoi(match[1])
+	}
 
-// KillMiningProcess stops all running xenblocksMiner processes
-func KillMiningProcess() {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("taskkill", "/F", "/IM", "SRBMiner-MULTI*")
-	} else {
-		cmd = exec.Command("pkill", "-f", "SRBMiner-MULTI")
+	switch {
+	case strings.Contains(line, "Mining:"):
+		return miner.LineReport
+	case strings.Contains(line, "Ecosystem"):
+		return miner.LineSkip
+	default:
+		return miner.LineShow
 	}
-	_ = cmd.Run()
 }
 
-// IsMining returns the current mining status
-func IsMining() bool {
-	return isMining
+func (Adapter) Install(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc) {
+	InstallSrbMiner(app, logView, logMessage)
 }
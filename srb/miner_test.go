@@ -0,0 +1,86 @@
+package xenblocks
+
+import (
+	"math"
+	"testing"
+	"xoon/miner"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		wantHashrate float64
+		wantAccepted int
+		wantRejected int
+	}{
+		{
+			name: "result lines count shares",
+			lines: []string{
+				"[2024-05-01 10:00:01] blake3_alephium: GPU0 result accepted [12ms]",
+				"[2024-05-01 10:00:05] blake3_alephium: GPU1 result accepted [11ms]",
+				"[2024-05-01 10:00:09] blake3_alephium: GPU0 result rejected [reason: stale share]",
+			},
+			wantAccepted: 2,
+			wantRejected: 1,
+		},
+		{
+			name: "summary totals replace the counts",
+			lines: []string{
+				"[2024-05-01 10:00:01] blake3_alephium: GPU0 result accepted [12ms]",
+				"[2024-05-01 10:01:00] Total: 1.25 GH/s | Accepted: 40 | Rejected: 3",
+				"[2024-05-01 10:01:00] Total: 1.25 GH/s | Accepted: 40 | Rejected: 3",
+			},
+			wantHashrate: 1.25e9,
+			wantAccepted: 40,
+			wantRejected: 3,
+		},
+		{
+			name: "other lines mentioning shares are not counted",
+			lines: []string{
+				"[2024-05-01 10:00:00] Pool accepted the login, rejected shares are reported",
+				"[2024-05-01 10:00:00] Job accepted from blake3.unmineable.com",
+			},
+		},
+		{
+			name:         "hashrate",
+			lines:        []string{"[2024-05-01 10:01:00] GPU total speed: 845.3 MH/s"},
+			wantHashrate: 845.3e6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats miner.Stats
+			for _, line := range tt.lines {
+				Adapter{}.ParseLine(line, &stats)
+			}
+			if math.Abs(stats.Hashrate-tt.wantHashrate) > 1e-3 {
+				t.Errorf("hashrate = %v, want %v", stats.Hashrate, tt.wantHashrate)
+			}
+			if stats.Accepted != tt.wantAccepted || stats.Rejected != tt.wantRejected {
+				t.Errorf("shares = %d/%d, want %d/%d", stats.Accepted, stats.Rejected, tt.wantAccepted, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestArgsOpenAPI(t *testing.T) {
+	hasAPI := func(args []string) bool {
+		for _, arg := range args {
+			if arg == "--api-enable" {
+				return true
+			}
+		}
+		return false
+	}
+
+	config := miner.Config{User: "SOL:address.xoon", Algorithm: "Blake3 (GPU>4GB)", Port: "3333"}
+	if hasAPI(Adapter{}.Args(config)) {
+		t.Error("API enabled without OpenAPI")
+	}
+	config.OpenAPI = true
+	if !hasAPI(Adapter{}.Args(config)) {
+		t.Error("API not enabled with OpenAPI")
+	}
+}
@@ -15,8 +15,9 @@ const SOLXEN_CPU_MINER_STRING = "SOL Miner (CPU)"
 const SOLXEN_NVIDIA_GPU_MINER_STRING = "SOL Miner (NVIDIA GPU)"
 const SOLXEN_AMD_GPU_MINER_STRING = "SOL Miner (AMD GPU)"
 const TOKEN_HARVEST_STRING = "Token Harvest (LFH)"
+const UNMINEABLE_WORKERS_STRING = "unMineable Workers"
 
-var ModuleNames = []string{WALLET_STRING, SOLXEN_CPU_MINER_STRING, SOLXEN_NVIDIA_GPU_MINER_STRING, SOLXEN_AMD_GPU_MINER_STRING, TOKEN_HARVEST_STRING}
+var ModuleNames = []string{WALLET_STRING, SOLXEN_CPU_MINER_STRING, SOLXEN_NVIDIA_GPU_MINER_STRING, SOLXEN_AMD_GPU_MINER_STRING, TOKEN_HARVEST_STRING, UNMINEABLE_WORKERS_STRING}
 
 type ModuleUI struct {
 	DashboardFlex *tview.Flex
@@ -31,6 +32,13 @@ var (
 
 var walletInfoView *tview.TextView
 
+// modalRoot is restored as the application root when a modal dialog closes
+var modalRoot tview.Primitive
+
+func SetModalRoot(root tview.Primitive) {
+	modalRoot = root
+}
+
 func GetDashboardFlex(app *tview.Application) *tview.Flex {
 	dashboardMutex.Lock()
 	defer dashboardMutex.Unlock()
@@ -63,6 +71,12 @@ func createDashboardFlex(app *tview.Application) *tview.Flex {
 		SetRegions(true).
 		SetWrap(false)
 
+	// Create a new text view for the portfolio value
+	portfolioView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWrap(true)
+
 	// Function to update Unmineable info
 	updateUnmineableInfo := func() {
 		// Check if GLOBAL_PUBLIC_KEY is empty
@@ -71,7 +85,19 @@ func createDashboardFlex(app *tview.Application) *tview.Flex {
 		}
 
 		go func() {
-			info, err := utils.GetUnmineableInfo(utils.GetGlobalPublicKey(), "SOL")
+			coin, address, err := utils.GetPayout()
+			if err != nil {
+				app.QueueUpdateDraw(func() {
+					unmineableInfoView.SetText(fmt.Sprintf("Invalid payout: %v", err))
+				})
+				return
+			}
+
+			info, err := utils.GetUnmineableInfo(address, coin)
+
+			// The portfolio is valued without the pending balance if it's unknown
+			updatePortfolio(app, portfolioView, info)
+
 			if err != nil {
 				app.QueueUpdateDraw(func() {
 					unmineableInfoView.SetText(fmt.Sprintf("Error: %v", err))
@@ -84,32 +110,55 @@ func createDashboardFlex(app *tview.Application) *tview.Flex {
 				autoPay = "On"
 			}
 
-			// Get solXEN equivalent
-			solXENAmount, err := utils.GetTokenExchangeAmount(info.Balance, utils.SolXEN)
-			if err != nil {
-				utils.LogToFile(fmt.Sprintf("Error getting solXEN amount: %v", err))
-				solXENAmount = "0" // Set to 0 if there's an error
+			var line1, line2 string
+			lin0 := "MINING STATS: (no referral)"
+			if referralCode := utils.GetReferralCode(); referralCode != "" {
+				lin0 = fmt.Sprintf("MINING STATS: (referral %s)", referralCode)
+			}
+			if coin == utils.SOL {
+				// Get solXEN equivalent
+				solXENAmount, err := utils.GetTokenExchangeAmount(info.Balance, utils.SolXEN)
+				if err != nil {
+					utils.LogToFile(fmt.Sprintf("Error getting solXEN amount: %v", err))
+					solXENAmount = "0" // Set to 0 if there's an error
+				}
+
+				// First line
+				line1 = fmt.Sprintf("Pending: %s %s (%s solXEN) | AutoPay: %s | PayOn: %s %s",
+					info.Balance, info.Coin,
+					solXENAmount,
+					autoPay,
+					info.PaymentThreshold, info.Coin)
+
+				// Second line - calculate rewards in solXEN
+				solXEN24h, _ := utils.GetTokenExchangeAmount(info.Past24h, utils.SolXEN)
+				solXEN7d, _ := utils.GetTokenExchangeAmount(info.Past7d, utils.SolXEN)
+				solXEN30d, _ := utils.GetTokenExchangeAmount(info.Past30d, utils.SolXEN)
+
+				line2 = fmt.Sprintf("Rewards: 24h: %s solXEN | 7d: %s solXEN | 30d: %s solXEN",
+					solXEN24h, solXEN7d, solXEN30d)
+			} else {
+				// solXEN conversions only apply to a SOL payout
+				line1 = fmt.Sprintf("Pending: %s %s | AutoPay: %s | PayOn: %s %s",
+					info.Balance, info.Coin,
+					autoPay,
+					info.PaymentThreshold, info.Coin)
+
+				line2 = fmt.Sprintf("Rewards: 24h: %s %s | 7d: %s %s | 30d: %s %s",
+					info.Past24h, info.Coin, info.Past7d, info.Coin, info.Past30d, info.Coin)
 			}
-
-			// First line
-			lin0 := "MINING STATS:"
-			line1 := fmt.Sprintf("Pending: %s %s (%s solXEN) | AutoPay: %s | PayOn: %s %s",
-				info.Balance, info.Coin,
-				solXENAmount,
-				autoPay,
-				info.PaymentThreshold, info.Coin)
-
-			// Second line - calculate rewards in solXEN
-			solXEN24h, _ := utils.GetTokenExchangeAmount(info.Past24h, utils.SolXEN)
-			solXEN7d, _ := utils.GetTokenExchangeAmount(info.Past7d, utils.SolXEN)
-			solXEN30d, _ := utils.GetTokenExchangeAmount(info.Past30d, utils.SolXEN)
-
-			line2 := fmt.Sprintf("Rewards: 24h: %s solXEN | 7d: %s solXEN | 30d: %s solXEN",
-				solXEN24h, solXEN7d, solXEN30d)
 
 			// Combine both lines
 			infoText := lin0 + "\n" + line1 + "\n" + line2
 
+			// Projection from the recent reward rate
+			forecast, err := utils.GetEarningsForecast(info)
+			if err != nil {
+				utils.LogToFile(fmt.Sprintf("Error forecasting earnings: %v", err))
+			} else {
+				infoText += "\n" + forecast.String()
+			}
+
 			app.QueueUpdateDraw(func() {
 				unmineableInfoView.SetText(infoText)
 			})
@@ -147,6 +196,7 @@ func createDashboardFlex(app *tview.Application) *tview.Flex {
 	// Add the Unmineable info view to the flex
 	dashboardFlex.AddItem(unmineableInfoView, 0, 1, false)
 	dashboardFlex.AddItem(walletInfoView, 0, 1, false)
+	dashboardFlex.AddItem(portfolioView, 0, 1, false)
 
 	dashboardFlex.SetBorder(true).SetTitle("Dashboard")
 	return dashboardFlex
@@ -176,6 +226,8 @@ func CreateConfigFlex(title string, app *tview.Application, logView *tview.TextV
 		return CreateSolXENAMDGPUConfigFlex(app, logView)
 	case TOKEN_HARVEST_STRING:
 		return CreateTokenHarvestConfigFlex(app, logView)
+	case UNMINEABLE_WORKERS_STRING:
+		return CreateUnmineableWorkersConfigFlex(app, logView)
 
 	default:
 		return createDefaultConfigFlex(title, app, logView)
@@ -203,6 +255,44 @@ func CreateSwitchViewFunc(rightFlex *tview.Flex, mainMenu *tview.List) func(*tvi
 	}
 }
 
+// CreateTransactionConfirmer returns a confirm function that shows the
+// transaction preview in a modal and waits for the user's choice.
+// It blocks, so it must not be called from the UI goroutine.
+func CreateTransactionConfirmer(app *tview.Application) utils.ConfirmTransactionFunc {
+	return func(preview *utils.SimulationPreview) bool {
+		// A failing simulation can only be cancelled
+		return ShowConfirmModal(app, preview.String(), !preview.Failed())
+	}
+}
+
+// ShowConfirmModal shows text in a modal and blocks until the user confirms
+// or cancels. It must not be called from the UI goroutine.
+func ShowConfirmModal(app *tview.Application, text string, allowConfirm bool) bool {
+	result := make(chan bool, 1)
+
+	app.QueueUpdateDraw(func() {
+		focus := app.GetFocus()
+
+		buttons := []string{"Confirm", "Cancel"}
+		if !allowConfirm {
+			buttons = []string{"Cancel"}
+		}
+
+		modal := tview.NewModal().
+			SetText(text).
+			AddButtons(buttons).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				app.SetRoot(modalRoot, true)
+				app.SetFocus(focus)
+				result <- buttonLabel == "Confirm"
+			})
+
+		app.SetRoot(modal, false)
+	})
+
+	return <-result
+}
+
 func UpdateButtonLabel(flex *tview.Flex, buttonName string, newLabel string) {
 	for i := 0; i < flex.GetItemCount(); i++ {
 		item := flex.GetItem(i)
@@ -215,6 +305,38 @@ func UpdateButtonLabel(flex *tview.Flex, buttonName string, newLabel string) {
 	}
 }
 
+// buildMiningUser saves the referral code of a miner form and builds the
+// miner user from it and the payout, logging why it can't
+func buildMiningUser(logView *tview.TextView, workerName string, referralCode string) (string, bool) {
+	if err := utils.SetReferralCode(referralCode); err != nil {
+		utils.LogMessage(logView, "Error saving referral code: "+err.Error())
+		return "", false
+	}
+
+	miningUser, err := utils.GetMiningUser(workerName)
+	if err != nil {
+		utils.LogMessage(logView, "Invalid payout: "+err.Error())
+		return "", false
+	}
+	return miningUser, true
+}
+
+// updatePortfolio values the wallet and the pending mining balance in USD
+func updatePortfolio(app *tview.Application, portfolioView *tview.TextView, pending *utils.UnmineableInfo) {
+	portfolio, err := utils.GetPortfolio(utils.GetGlobalPublicKey(), pending)
+	if err != nil {
+		utils.LogToFile(fmt.Sprintf("Error valuing portfolio: %v", err))
+		app.QueueUpdateDraw(func() {
+			portfolioView.SetText(fmt.Sprintf("Error valuing portfolio: %v", err))
+		})
+		return
+	}
+
+	app.QueueUpdateDraw(func() {
+		portfolioView.SetText(portfolio.String())
+	})
+}
+
 // Function to update wallet info
 func UpdateWalletInfo(app *tview.Application, walletInfoView *tview.TextView) {
 	if utils.GetGlobalPublicKey() == "" {
@@ -255,7 +377,7 @@ func UpdateWalletInfo(app *tview.Application, walletInfoView *tview.TextView) {
 		}
 
 		// Write balances in the specified order
-		for _, symbol := range tokenOptions {
+		for _, symbol := range utils.GetEnabledTokenSymbols() {
 			if balance, exists := balanceMap[symbol]; exists {
 				infoText.WriteString(fmt.Sprintf(" | %.6f %s ", balance.Balance, balance.Symbol))
 			} else {
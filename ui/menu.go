@@ -3,8 +3,8 @@ package ui
 import (
 	"fmt"
 	"time"
+	"xoon/miner"
 	"xoon/utils"
-	xenblocks "xoon/xmrig"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -24,6 +24,7 @@ func CreateMainMenu() *tview.List {
 			UpdateAMDGPUMinerPublicKeyTextView() // Update the Public Key text view
 		}).
 		AddItem(TOKEN_HARVEST_STRING, "", 'f', nil).
+		AddItem(UNMINEABLE_WORKERS_STRING, "", 'g', nil).
 		AddItem("QUIT(Press 'q' 4 times)", "", 'q', nil).
 		AddItem("", "by @xen_artist", 0, nil)
 
@@ -67,7 +68,7 @@ func SetupInputCapture(app *tview.Application, funcToRun func()) {
 			}
 			lastQuitTime = now
 			if quitCount >= 4 {
-				xenblocks.KillMiningProcess() // Kill the mining process before exiting
+				miner.StopAll() // Stop the miners before exiting
 				utils.ClearGlobalKeys()
 				app.Stop()
 				return nil
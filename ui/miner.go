@@ -0,0 +1,236 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"xoon/miner"
+	"xoon/utils"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// How often the stats panels poll the API of a running miner
+const minerAPIInterval = 5 * time.Second
+
+// pollMinerAPI calls update every minerAPIInterval with the status of the miner
+func pollMinerAPI(m *miner.Supervisor, update func(status miner.Status)) {
+	go func() {
+		ticker := time.NewTicker(minerAPIInterval)
+		for range ticker.C {
+			update(m.Status())
+		}
+	}()
+}
+
+// withRestarts adds the watchdog restarts of the miner to a panel summary
+func withRestarts(summary string, status miner.Status) string {
+	if status.Restarts == 0 {
+		return summary
+	}
+	return summary + fmt.Sprintf("\n[yellow]Restarts: %d[-] | last at %s: %s",
+		status.Restarts, status.LastRestart.Format("15:04:05"), tview.Escape(status.LastRestartReason))
+}
+
+// createMinerForm adds the settings and buttons of a miner to its form,
+// after the Public Key text view the form starts with
+func createMinerForm(form *tview.Form, m *miner.Supervisor, logView *tview.TextView) {
+	adapter := m.Adapter()
+
+	config := miner.Config{Threads: "1"}
+	workerName := "xoon"
+	referralCode := utils.GetReferralCode()
+	stallMinutes := miner.GetSettings().StallMinutes
+	stallOptions, stallIndex := generateStallOptions(stallMinutes)
+
+	if adapter.UsesThreads() {
+		form.AddDropDown("CPU Threads", generateThreadOptions(), 0, func(option string, index int) {
+			config.Threads = option
+		})
+	}
+
+	form.
+		AddDropDown("Mining Algorithm", adapter.Algorithms(), 0, func(option string, index int) {
+			config.Algorithm = option
+		}).
+		AddDropDown("Port", adapter.Ports(), 0, func(option string, index int) {
+			config.Port = option
+		}).
+		AddInputField("Worker Name", workerName, 10, nil, func(text string) {
+			workerName = text
+		}).
+		AddInputField("Referral Code (empty = none)", referralCode, 10, nil, func(text string) {
+			referralCode = text
+		}).
+		AddDropDown("Restart When Stalled", stallOptions, stallIndex, func(option string, index int) {
+			stallMinutes, _ = strconv.Atoi(strings.TrimSuffix(option, " min"))
+		}).
+		AddButton("Install Miner", func() { m.Install() }).
+		AddButton("Start Mining", func() {
+			if m.Status().Running {
+				return
+			}
+			miningUser, ok := buildMiningUser(logView, workerName, referralCode)
+			if !ok {
+				return
+			}
+			config.User = miningUser
+			if err := miner.SetStallMinutes(stallMinutes); err != nil {
+				utils.LogMessage(logView, "Error saving watchdog settings: "+err.Error())
+				return
+			}
+			settings := miner.GetSettings()
+			config.StallWindow, config.MaxRestartsPerHour = settings.StallWindow(), settings.MaxRestartsPerHour
+			config.OpenAPI = settings.SRBMinerAPI
+			if err := m.Start(config); err != nil {
+				utils.LogMessage(logView, "Error starting miner: "+err.Error())
+			}
+		}).
+		AddButton("Stop Mining", func() {
+			// Also drops a pending watchdog restart of a miner that exited
+			if err := m.Stop(); err != nil {
+				utils.LogMessage(logView, err.Error())
+			}
+		})
+}
+
+// generateStallOptions lists the watchdog stall windows with the saved one
+// selected, "Off" only restarts a miner when it exits
+func generateStallOptions(stallMinutes int) ([]string, int) {
+	options := []string{"Off"}
+	index := 0
+	for _, minutes := range []int{5, 10, 15, 30, 60} {
+		if minutes == stallMinutes {
+			index = len(options)
+		}
+		options = append(options, fmt.Sprintf("%d min", minutes))
+	}
+	// A window set in the config file
+	if index == 0 && stallMinutes > 0 {
+		index = len(options)
+		options = append(options, fmt.Sprintf("%d min", stallMinutes))
+	}
+	return options, index
+}
+
+// formatAPIHashrate formats a hashrate from a miner API, 0 before it's measured
+func formatAPIHashrate(hashrate float64) string {
+	if hashrate == 0 {
+		return "n/a"
+	}
+	return formatHashrate(hashrate)
+}
+
+// createGPUPanel shows a summary and the GPU table of a running miner,
+// polled from its API by getStats
+func createGPUPanel(app *tview.Application, m *miner.Supervisor, apiName string, getStats func() (string, []miner.DeviceStats, error)) *tview.Flex {
+	summaryView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false).
+		SetText("Not mining")
+
+	gpuTable := tview.NewTable().
+		SetBorders(false).
+		SetFixed(1, 0)
+
+	panel := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(summaryView, 4, 0, false).
+		AddItem(gpuTable, 0, 1, false)
+	panel.SetBorder(true).
+		SetTitle("GPUs").
+		SetTitleAlign(tview.AlignLeft)
+
+	pollMinerAPI(m, func(status miner.Status) {
+		if !status.Running {
+			app.QueueUpdateDraw(func() {
+				summaryView.SetText(withRestarts("Not mining", status))
+				gpuTable.Clear()
+			})
+			return
+		}
+
+		summary, gpus, err := getStats()
+		if err != nil {
+			// GPU miners serve the API once the GPUs are set up
+			app.QueueUpdateDraw(func() {
+				summaryView.SetText(withRestarts(fmt.Sprintf("Waiting for %s API...\n%v", apiName, err), status))
+			})
+			return
+		}
+
+		app.QueueUpdateDraw(func() {
+			summaryView.SetText(withRestarts(summary, status))
+			fillGPUTable(gpuTable, gpus)
+		})
+	})
+
+	return panel
+}
+
+func formatGPUShares(accepted int, rejected int, stale int) string {
+	return fmt.Sprintf("Shares: [green]%d[-] accepted, [red]%d[-] rejected, %d stale", accepted, rejected, stale)
+}
+
+// GPU temperatures shown as a warning and as critical, in °C
+const (
+	gpuTempWarning  = 70
+	gpuTempCritical = 80
+)
+
+// fillGPUTable shows the figures of the GPUs reported by a miner API. Hot
+// cards and cards without hashrate are colored so they stand out.
+func fillGPUTable(table *tview.Table, gpus []miner.DeviceStats) {
+	table.Clear()
+
+	headers := []string{"GPU", "Name", "Hashrate", "Shares A/R/S", "Temp", "Fan", "Power", "Efficiency"}
+	for column, header := range headers {
+		table.SetCell(0, column, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for i, gpu := range gpus {
+		hashrateColor := tcell.ColorWhite
+		if gpu.Hashrate == 0 {
+			hashrateColor = tcell.ColorRed
+		}
+		tempColor := tcell.ColorGreen
+		switch {
+		case gpu.Temperature >= gpuTempCritical:
+			tempColor = tcell.ColorRed
+		case gpu.Temperature >= gpuTempWarning:
+			tempColor = tcell.ColorYellow
+		}
+
+		efficiency := "n/a"
+		if gpu.Efficiency() > 0 {
+			efficiency = strings.TrimSuffix(formatHashrate(gpu.Efficiency()), "/s") + "/W"
+		}
+
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(strconv.Itoa(gpu.Index)).SetExpansion(1))
+		table.SetCell(row, 1, tview.NewTableCell(gpu.Name).SetExpansion(1))
+		table.SetCell(row, 2, tview.NewTableCell(formatAPIHashrate(gpu.Hashrate)).SetTextColor(hashrateColor).SetExpansion(1))
+		table.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf("%d/%d/%d", gpu.Accepted, gpu.Rejected, gpu.Stale)).SetExpansion(1))
+		table.SetCell(row, 4, tview.NewTableCell(formatGPUReading(gpu.Temperature, "°C")).SetTextColor(tempColor).SetExpansion(1))
+		table.SetCell(row, 5, tview.NewTableCell(formatGPUReading(gpu.FanSpeed, "%")).SetExpansion(1))
+		table.SetCell(row, 6, tview.NewTableCell(formatGPUReading(gpu.Power, " W")).SetExpansion(1))
+		table.SetCell(row, 7, tview.NewTableCell(efficiency).SetExpansion(1))
+	}
+
+	if len(gpus) == 0 {
+		table.SetCell(1, 0, tview.NewTableCell("No GPUs reported"))
+	}
+}
+
+// formatGPUReading formats a sensor reading, "n/a" if the miner doesn't report it
+func formatGPUReading(value float64, unit string) string {
+	if value == 0 {
+		return "n/a"
+	}
+	return strconv.FormatFloat(value, 'f', 0, 64) + unit
+}
@@ -1,6 +1,9 @@
 package ui
 
 import (
+	"fmt"
+	"time"
+	"xoon/miner"
 	xenblocks "xoon/srb"
 	"xoon/utils"
 
@@ -22,39 +25,72 @@ func CreateSolXENAMDGPUUI(app *tview.Application) ModuleUI {
 		publicKeyDisplay = utils.GetGlobalPublicKey()[:8] + "********"
 	}
 
-	var selectedAlgorithm, selectedPort, workerName string
-
-	solxenamdgpuForm.AddTextView("Public Key", publicKeyDisplay, 0, 1, false, true).
-		AddDropDown("Mining Algorithm", xenblocks.GPUAlgorithms, 0, func(option string, index int) {
-			selectedAlgorithm = option
-		}).
-		AddDropDown("Port", xenblocks.GPUMiningPorts, 0, func(option string, index int) {
-			selectedPort = option
-		}).
-		AddInputField("Worker Name", "xoon", 10, nil, func(text string) {
-			workerName = text
-		}).
-		AddButton("Install Miner", func() { xenblocks.InstallSrbMiner(app, moduleUI.LogView, utils.LogMessage) }).
-		AddButton("Start Mining", func() {
-			if !xenblocks.IsMining() {
-				publicKey := utils.GetGlobalPublicKey()
-				xenblocks.StartMining(app, moduleUI.LogView, utils.LogMessage,
-					publicKey, selectedAlgorithm, selectedPort, workerName)
-			}
-		}).
-		AddButton("Stop Mining", func() {
-			if xenblocks.IsMining() {
-				xenblocks.StopMining(app, moduleUI.LogView, utils.LogMessage)
-			}
-		})
-
-	contentFlex := tview.NewFlex().AddItem(solxenamdgpuForm, 0, 1, true)
+	amdMiner := miner.NewSupervisor(app, xenblocks.Adapter{}, moduleUI.LogView, utils.LogMessage)
+
+	solxenamdgpuForm.AddTextView("Public Key", publicKeyDisplay, 0, 1, false, true)
+	createMinerForm(solxenamdgpuForm, amdMiner, moduleUI.LogView)
+
+	panel := createAMDGPUPanel(app, amdMiner, xenblocks.NewAPIClient())
+
+	// SRBMiner can't bind its API to localhost, so it is opt-in
+	solxenamdgpuForm.AddCheckbox("SRBMiner API (open to network)", miner.GetSettings().SRBMinerAPI, func(checked bool) {
+		if err := miner.SetSRBMinerAPI(checked); err != nil {
+			utils.LogMessage(moduleUI.LogView, "Error saving miner config: "+err.Error())
+			return
+		}
+		setAMDGPUPanelTitle(panel, checked)
+		if checked {
+			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("WARNING: the SRBMiner API can't be bound to localhost, it listens on port %d of every network interface. "+
+				"Other hosts on your network can read the mining stats, block the port in your firewall. Applies when mining is next started.", xenblocks.API_PORT))
+		} else {
+			utils.LogMessage(moduleUI.LogView, "SRBMiner API turned off, the stats are read from the miner output when mining is next started")
+		}
+	})
+
+	contentFlex := tview.NewFlex().
+		AddItem(solxenamdgpuForm, 0, 1, true).
+		AddItem(panel, 0, 2, false)
 
 	moduleUI.ConfigFlex.AddItem(contentFlex, 0, 1, true)
 
 	return moduleUI
 }
 
+// createAMDGPUPanel shows the live stats of the SRBMiner API while mining.
+// SRBMiner can't bind its API to localhost, so it is only started when the
+// user turns it on, the stats are otherwise parsed from the miner output.
+func createAMDGPUPanel(app *tview.Application, amdMiner *miner.Supervisor, client *xenblocks.APIClient) *tview.Flex {
+	panel := createGPUPanel(app, amdMiner, "SRBMiner", func() (string, []miner.DeviceStats, error) {
+		if !amdMiner.Status().Config.OpenAPI {
+			stats := amdMiner.Stats()
+			summary := fmt.Sprintf("SRBMiner API off, stats from the miner output\nTotal: %s | %s",
+				formatAPIHashrate(stats.Hashrate), formatGPUShares(stats.Accepted, stats.Rejected, 0))
+			return summary, nil, nil
+		}
+
+		stats, err := client.GetStats()
+		if err != nil {
+			return "", nil, err
+		}
+		summary := fmt.Sprintf("SRBMiner %s | %s | up %s\nTotal: %s | %s\nPool: %s | Latency: %s",
+			stats.Version, stats.Algorithm, stats.Uptime.Round(time.Second),
+			formatAPIHashrate(stats.Hashrate), formatGPUShares(stats.Accepted, stats.Rejected, stats.Stale),
+			stats.Pool, stats.PoolLatency)
+		return summary, stats.GPUs, nil
+	})
+	setAMDGPUPanelTitle(panel, miner.GetSettings().SRBMinerAPI)
+	return panel
+}
+
+// setAMDGPUPanelTitle says whether the SRBMiner API is open to the network
+func setAMDGPUPanelTitle(panel *tview.Flex, apiEnabled bool) {
+	if apiEnabled {
+		panel.SetTitle(fmt.Sprintf("GPUs (SRBMiner API open to the network on port %d, read only)", xenblocks.API_PORT))
+	} else {
+		panel.SetTitle("GPUs (SRBMiner API off)")
+	}
+}
+
 func CreateSolXENAMDGPUConfigFlex(app *tview.Application, logView *tview.TextView) *tview.Flex {
 	configFlex := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
@@ -1,12 +1,16 @@
 package ui
 
 import (
+	"fmt"
+	"xoon/miner"
 	"xoon/utils"
 	xenblocks "xoon/xmrig"
 
 	"runtime"
 	"strconv"
+	"time"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
@@ -37,43 +41,107 @@ func CreateSolXENCPUUI(app *tview.Application) ModuleUI {
 		publicKeyDisplay = utils.GetGlobalPublicKey()[:8] + "********"
 	}
 
-	var selectedAlgorithm, selectedPort, workerName string
-	var selectedThreads string = "1" // Default value
-
-	solxencpuForm.AddTextView("Public Key", publicKeyDisplay, 0, 1, false, true).
-		AddDropDown("CPU Threads", generateThreadOptions(), 0, func(option string, index int) {
-			selectedThreads = option
-		}).
-		AddDropDown("Mining Algorithm", xenblocks.CPUAlgorithms, 0, func(option string, index int) {
-			selectedAlgorithm = option
-		}).
-		AddDropDown("Port", xenblocks.CPUMiningPorts, 0, func(option string, index int) {
-			selectedPort = option
-		}).
-		AddInputField("Worker Name", "xoon", 10, nil, func(text string) {
-			workerName = text
-		}).
-		AddButton("Install Miner", func() { xenblocks.InstallXmrig(app, moduleUI.LogView, utils.LogMessage) }).
-		AddButton("Start Mining", func() {
-			if !xenblocks.IsMining() {
-				publicKey := utils.GetGlobalPublicKey()
-				xenblocks.StartMining(app, moduleUI.LogView, utils.LogMessage,
-					publicKey, selectedThreads, selectedAlgorithm, selectedPort, workerName)
-			}
-		}).
-		AddButton("Stop Mining", func() {
-			if xenblocks.IsMining() {
-				xenblocks.StopMining(app, moduleUI.LogView, utils.LogMessage)
-			}
-		})
+	cpuMiner := miner.NewSupervisor(app, xenblocks.Adapter{}, moduleUI.LogView, utils.LogMessage)
+
+	solxencpuForm.AddTextView("Public Key", publicKeyDisplay, 0, 1, false, true)
+	createMinerForm(solxencpuForm, cpuMiner, moduleUI.LogView)
 
-	contentFlex := tview.NewFlex().AddItem(solxencpuForm, 0, 1, true)
+	contentFlex := tview.NewFlex().
+		AddItem(solxencpuForm, 0, 1, true).
+		AddItem(createCPUStatsPanel(app, cpuMiner), 0, 1, false)
 
 	moduleUI.ConfigFlex.AddItem(contentFlex, 0, 1, true)
 
 	return moduleUI
 }
 
+// createCPUStatsPanel shows the live stats of the XMRig API while mining
+func createCPUStatsPanel(app *tview.Application, cpuMiner *miner.Supervisor) *tview.Flex {
+	summaryView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false).
+		SetText("Not mining")
+
+	threadsTable := tview.NewTable().
+		SetBorders(false).
+		SetFixed(1, 0)
+
+	panel := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(summaryView, 8, 0, false).
+		AddItem(threadsTable, 0, 1, false)
+	panel.SetBorder(true).
+		SetTitle("CPU Stats").
+		SetTitleAlign(tview.AlignLeft)
+
+	client := xenblocks.NewAPIClient()
+	pollMinerAPI(cpuMiner, func(status miner.Status) {
+		if !status.Running {
+			app.QueueUpdateDraw(func() {
+				summaryView.SetText(withRestarts("Not mining", status))
+				threadsTable.Clear()
+			})
+			return
+		}
+
+		stats, err := client.GetStats()
+		if err != nil {
+			// XMRig takes a while to serve the API after starting
+			app.QueueUpdateDraw(func() {
+				summaryView.SetText(withRestarts("Waiting for XMRig API...\n"+err.Error(), status))
+			})
+			return
+		}
+
+		app.QueueUpdateDraw(func() {
+			summaryView.SetText(withRestarts(formatCPUStats(stats), status))
+			fillThreadsTable(threadsTable, stats.Threads)
+		})
+	})
+
+	return panel
+}
+
+func formatCPUStats(stats *xenblocks.APIStats) string {
+	shares := fmt.Sprintf("[green]%d[-] accepted, [red]%d[-] rejected", stats.Accepted, stats.Rejected)
+	if total := stats.Accepted + stats.Rejected; total > 0 {
+		shares += fmt.Sprintf(" (%.1f%%)", float64(stats.Accepted)/float64(total)*100)
+	}
+
+	return fmt.Sprintf("XMRig %s | %s | up %s\n", stats.Version, stats.Algorithm, stats.Uptime.Round(time.Second)) +
+		fmt.Sprintf("Hashrate 10s: %s | 60s: %s | 15m: %s\n", formatAPIHashrate(stats.Hashrate10s), formatAPIHashrate(stats.Hashrate60s), formatAPIHashrate(stats.Hashrate15m)) +
+		fmt.Sprintf("Max: %s\n", formatAPIHashrate(stats.HashrateMax)) +
+		fmt.Sprintf("Shares: %s\n", shares) +
+		fmt.Sprintf("Difficulty: %d\n", stats.Difficulty) +
+		fmt.Sprintf("Pool: %s | Latency: %s", stats.Pool, stats.PoolLatency)
+}
+
+func fillThreadsTable(table *tview.Table, threads []xenblocks.ThreadStats) {
+	table.Clear()
+
+	headers := []string{"Thread", "CPU", "10s", "60s", "15m"}
+	for column, header := range headers {
+		table.SetCell(0, column, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for i, thread := range threads {
+		cpu := "-"
+		if thread.Affinity >= 0 {
+			cpu = strconv.Itoa(thread.Affinity)
+		}
+
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(strconv.Itoa(i)).SetExpansion(1))
+		table.SetCell(row, 1, tview.NewTableCell(cpu).SetExpansion(1))
+		table.SetCell(row, 2, tview.NewTableCell(formatAPIHashrate(thread.Hashrate10s)).SetExpansion(1))
+		table.SetCell(row, 3, tview.NewTableCell(formatAPIHashrate(thread.Hashrate60s)).SetExpansion(1))
+		table.SetCell(row, 4, tview.NewTableCell(formatAPIHashrate(thread.Hashrate15m)).SetExpansion(1))
+	}
+}
+
 func CreateSolXENCPUConfigFlex(app *tview.Application, logView *tview.TextView) *tview.Flex {
 	configFlex := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
@@ -1,7 +1,10 @@
 package ui
 
 import (
+	"fmt"
+	"time"
 	xenblocks "xoon/lol"
+	"xoon/miner"
 	"xoon/utils"
 
 	"github.com/rivo/tview"
@@ -22,39 +25,35 @@ func CreateSolXENNvidiaGPUUI(app *tview.Application) ModuleUI {
 		publicKeyDisplay = utils.GetGlobalPublicKey()[:8] + "********"
 	}
 
-	var selectedAlgorithm, selectedPort, workerName string
-
-	solxennvidiaForm.AddTextView("Public Key", publicKeyDisplay, 0, 1, false, true).
-		AddDropDown("Mining Algorithm", xenblocks.GPUAlgorithms, 0, func(option string, index int) {
-			selectedAlgorithm = option
-		}).
-		AddDropDown("Port", xenblocks.GPUMiningPorts, 0, func(option string, index int) {
-			selectedPort = option
-		}).
-		AddInputField("Worker Name", "xoon", 10, nil, func(text string) {
-			workerName = text
-		}).
-		AddButton("Install Miner", func() { xenblocks.InstallLolMiner(app, moduleUI.LogView, utils.LogMessage) }).
-		AddButton("Start Mining", func() {
-			if !xenblocks.IsMining() {
-				publicKey := utils.GetGlobalPublicKey()
-				xenblocks.StartMining(app, moduleUI.LogView, utils.LogMessage,
-					publicKey, selectedAlgorithm, selectedPort, workerName)
-			}
-		}).
-		AddButton("Stop Mining", func() {
-			if xenblocks.IsMining() {
-				xenblocks.StopMining(app, moduleUI.LogView, utils.LogMessage)
-			}
-		})
-
-	contentFlex := tview.NewFlex().AddItem(solxennvidiaForm, 0, 1, true)
+	nvidiaMiner := miner.NewSupervisor(app, xenblocks.Adapter{}, moduleUI.LogView, utils.LogMessage)
+
+	solxennvidiaForm.AddTextView("Public Key", publicKeyDisplay, 0, 1, false, true)
+	createMinerForm(solxennvidiaForm, nvidiaMiner, moduleUI.LogView)
+
+	contentFlex := tview.NewFlex().
+		AddItem(solxennvidiaForm, 0, 1, true).
+		AddItem(createNvidiaGPUPanel(app, nvidiaMiner), 0, 2, false)
 
 	moduleUI.ConfigFlex.AddItem(contentFlex, 0, 1, true)
 
 	return moduleUI
 }
 
+// createNvidiaGPUPanel shows the live stats of the lolMiner API while mining
+func createNvidiaGPUPanel(app *tview.Application, nvidiaMiner *miner.Supervisor) *tview.Flex {
+	client := xenblocks.NewAPIClient()
+	return createGPUPanel(app, nvidiaMiner, "lolMiner", func() (string, []miner.DeviceStats, error) {
+		stats, err := client.GetStats()
+		if err != nil {
+			return "", nil, err
+		}
+		summary := fmt.Sprintf("%s | %s | up %s\nTotal: %s | %s\nPool: %s",
+			stats.Software, stats.Algorithm, stats.Uptime.Round(time.Second),
+			formatAPIHashrate(stats.Hashrate), formatGPUShares(stats.Accepted, stats.Rejected, stats.Stale), stats.Pool)
+		return summary, stats.GPUs, nil
+	})
+}
+
 func CreateSolXENNvidiaGPUConfigFlex(app *tview.Application, logView *tview.TextView) *tview.Flex {
 	configFlex := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
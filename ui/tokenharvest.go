@@ -18,7 +18,8 @@ type BurnMemo struct {
 	Author  string `json:"author"`
 }
 
-var tokenOptions = []string{"solXEN", "xencat", "PV", "ORE"}
+// tokenListeners refresh the token dropdowns after the token registry changed
+var tokenListeners []func(tokens []string)
 
 // var autoHarvestCounter = 0
 
@@ -27,11 +28,20 @@ func CreateTokenHarvestUI(app *tview.Application) ModuleUI {
 
 	autoHarvestForm := createAutoHarvestForm(app, &moduleUI, walletInfoView)
 	manualHarvestForm := createManualHarvestForm(app, &moduleUI, walletInfoView)
+	swapForm := createSwapForm(app, &moduleUI, walletInfoView)
+	tokenRegistryForm := createTokenRegistryForm(app, &moduleUI, walletInfoView)
 
-	// Create a flex container for the two forms
+	// Stack the swap and token forms in the last column
+	swapFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(swapForm, 0, 2, false).
+		AddItem(tokenRegistryForm, 0, 1, false)
+
+	// Create a flex container for the forms
 	formsFlex := tview.NewFlex().
 		AddItem(autoHarvestForm, 0, 1, true).
-		AddItem(manualHarvestForm, 0, 1, false)
+		AddItem(manualHarvestForm, 0, 1, false).
+		AddItem(swapFlex, 0, 1, false)
 
 	// Add the forms flex to the content flex
 	contentFlex := tview.NewFlex().AddItem(formsFlex, 0, 1, true)
@@ -63,9 +73,13 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 		// Use default values if config file can't be read
 		config = utils.SolXENConfig{
 			// AutoHarvestActive: true,
-			SOLPerHarvest:   0.001,
-			TokenToHarvest:  "solXEN",
-			HarvestInterval: "Off",
+			SOLPerHarvest:     0.001,
+			TokenToHarvest:    "solXEN",
+			HarvestInterval:   "Off",
+			SlippageBps:       utils.DefaultSlippageBps,
+			MaxPriceImpactPct: utils.DefaultMaxPriceImpactPct,
+			TWAPSlices:        1,
+			TWAPWindow:        utils.DefaultTWAPWindow,
 		}
 	}
 
@@ -89,6 +103,7 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 	})
 
 	// 3. Dropdown for token selection
+	tokenOptions := utils.GetEnabledTokenSymbols()
 	tokenIndex := 0
 	for i, token := range tokenOptions {
 		if token == config.TokenToHarvest {
@@ -96,8 +111,13 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 			break
 		}
 	}
-	autoHarvestForm.AddDropDown("Token to Harvest", tokenOptions, tokenIndex, func(option string, index int) {
+	selectTokenToHarvest := func(option string, index int) {
 		config.TokenToHarvest = option
+	}
+	autoHarvestForm.AddDropDown("Token to Harvest", tokenOptions, tokenIndex, selectTokenToHarvest)
+	tokenDropDown := autoHarvestForm.GetFormItemByLabel("Token to Harvest").(*tview.DropDown)
+	onTokensChanged(func(tokens []string) {
+		setTokenDropDownOptions(tokenDropDown, tokens, selectTokenToHarvest)
 	})
 
 	// 4. Dropdown for harvest interval
@@ -113,7 +133,45 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 		config.HarvestInterval = option
 	})
 
-	// 5. Dropdown for burn interval
+	// 5. Slippage and price impact limit
+	addSwapLimitFields(autoHarvestForm, &config.SlippageBps, &config.MaxPriceImpactPct)
+
+	// 6. Optional price conditions, 0 means off
+	autoHarvestForm.AddInputField("Buy Below (SOL)", strconv.FormatFloat(config.MaxBuyPriceSOL, 'f', -1, 64), 16, tview.InputFieldFloat, func(text string) {
+		if val, err := strconv.ParseFloat(text, 64); err == nil && val >= 0 {
+			config.MaxBuyPriceSOL = val
+		}
+	})
+	autoHarvestForm.AddInputField("Buy After Drop (%)", strconv.FormatFloat(config.MinDropPct, 'f', -1, 64), 10, tview.InputFieldFloat, func(text string) {
+		if val, err := strconv.ParseFloat(text, 64); err == nil && val >= 0 {
+			config.MinDropPct = val
+		}
+	})
+	autoHarvestForm.AddInputField(fmt.Sprintf("Drop Window (h, max %d)", utils.MaxDropWindowHours), strconv.Itoa(config.DropWindowHours), 10, tview.InputFieldInteger, func(text string) {
+		if val, err := strconv.Atoi(text); err == nil && val >= 0 && val <= utils.MaxDropWindowHours {
+			config.DropWindowHours = val
+		}
+	})
+
+	// 7. TWAP mode, more than 1 slice spreads each harvest over the window
+	autoHarvestForm.AddInputField("TWAP Slices", strconv.Itoa(config.TWAPSlices), 10, tview.InputFieldInteger, func(text string) {
+		if val, err := strconv.Atoi(text); err == nil && val > 0 {
+			config.TWAPSlices = val
+		}
+	})
+	twapWindowOptions := []string{"10m", "1h", "6h", "24h"}
+	twapWindowIndex := 1 // default to 1h
+	for i, window := range twapWindowOptions {
+		if window == config.TWAPWindow {
+			twapWindowIndex = i
+			break
+		}
+	}
+	autoHarvestForm.AddDropDown("TWAP Window", twapWindowOptions, twapWindowIndex, func(option string, index int) {
+		config.TWAPWindow = option
+	})
+
+	// 8. Dropdown for burn interval
 	// burnOptions := []string{"Off", "Burn/69", "Burn/100", "Burn/420"}
 	// burnIndex := 0
 	// for i, option := range burnOptions {
@@ -129,7 +187,7 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 	// Add a channel to trigger config reload
 	reloadConfigChan := make(chan struct{})
 
-	// 6. Save Config button
+	// 9. Save Config button
 	autoHarvestForm.AddButton("Save Config & Auto Harvest", func() {
 		err := utils.WriteSolXENConfigFile(config)
 		if err != nil {
@@ -141,6 +199,14 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 		}
 	})
 
+	// Resume a TWAP order interrupted by a restart
+	if order, err := utils.LoadTWAPOrder(); err != nil {
+		utils.LogToFile("Failed to load TWAP order: " + err.Error())
+	} else if order != nil {
+		utils.LogMessage(moduleUI.LogView, "Resuming "+order.String())
+		go runTWAPOrder(app, moduleUI, walletInfoView, order)
+	}
+
 	go func() {
 		var ticker *time.Ticker
 		defer func() {
@@ -199,6 +265,24 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 			for {
 				select {
 				case <-ticker.C:
+					// Nothing to harvest while another coin is mined
+					if err := utils.CheckHarvestPayout(); err != nil {
+						utils.LogMessage(moduleUI.LogView, "Harvest skipped: "+err.Error())
+						break counterdownLoop
+					}
+
+					// Skip the harvest unless its price conditions are met
+					if err := utils.CheckHarvestConditions(config); err != nil {
+						utils.LogMessage(moduleUI.LogView, "Harvest skipped: "+err.Error())
+						break counterdownLoop
+					}
+
+					// A TWAP order spreads the previous harvest, don't stack another one on it
+					if utils.TWAPOrderActive() {
+						utils.LogMessage(moduleUI.LogView, "Harvest skipped: TWAP order still running")
+						break counterdownLoop
+					}
+
 					// Check wallet balance
 					balances, err := utils.GetWalletTokenBalances(utils.GetGlobalPublicKey())
 					if err != nil {
@@ -213,6 +297,12 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 						break counterdownLoop
 					}
 
+					// Unattended harvests are verified and simulated but not confirmed
+					swapOptions := utils.SwapOptions{
+						SlippageBps:       config.SlippageBps,
+						MaxPriceImpactPct: config.MaxPriceImpactPct,
+					}
+
 					// Get solXEN balance
 					solXENBalance := 0.0
 					for _, balance := range balances {
@@ -254,11 +344,11 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 							}
 
 							// Buy solXEN
-							result, err := utils.ExchangeSolForToken(solRequiredAmount, "solXEN")
+							result, err := utils.ExchangeSolForToken(solRequiredAmount, "solXEN", swapOptions)
 							if err != nil {
 								utils.LogMessage(moduleUI.LogView, "Error buying solXEN: "+err.Error())
 							} else {
-								utils.LogMessage(moduleUI.LogView, fmt.Sprintf("SOL: %s -> solXEN: %s successfully for governance purposes", solRequiredAmount, result))
+								utils.LogMessage(moduleUI.LogView, fmt.Sprintf("%s successfully for governance purposes", result))
 							}
 
 							// Wait for transaction to complete
@@ -283,11 +373,28 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 						config.SOLPerHarvest = 0.000001
 					}
 
-					result, err := utils.ExchangeSolForToken(strconv.FormatFloat(config.SOLPerHarvest, 'f', -1, 64), config.TokenToHarvest)
+					// Split the harvest into slices over the TWAP window
+					if config.TWAPSlices > 1 {
+						window, err := time.ParseDuration(config.TWAPWindow)
+						if err != nil {
+							utils.LogMessage(moduleUI.LogView, "Invalid TWAP window: "+config.TWAPWindow)
+							break counterdownLoop
+						}
+						order, err := utils.NewTWAPOrder(config.TokenToHarvest, config.SOLPerHarvest, config.TWAPSlices, window, config.SlippageBps, config.MaxPriceImpactPct)
+						if err != nil {
+							utils.LogMessage(moduleUI.LogView, "Error starting TWAP order: "+err.Error())
+							break counterdownLoop
+						}
+						utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Starting TWAP order: %f SOL for %s in %d slices over %s", config.SOLPerHarvest, config.TokenToHarvest, config.TWAPSlices, config.TWAPWindow))
+						go runTWAPOrder(app, moduleUI, walletInfoView, order)
+						break counterdownLoop
+					}
+
+					result, err := utils.ExchangeSolForToken(strconv.FormatFloat(config.SOLPerHarvest, 'f', -1, 64), config.TokenToHarvest, swapOptions)
 					if err != nil {
 						utils.LogMessage(moduleUI.LogView, "Error: "+err.Error())
 					} else {
-						utils.LogMessage(moduleUI.LogView, fmt.Sprintf("SOL: %v -> %s: %s successfully", config.SOLPerHarvest, config.TokenToHarvest, result))
+						utils.LogMessage(moduleUI.LogView, fmt.Sprintf("%s successfully", result))
 
 						// Increment counter and check for burn condition
 						// autoHarvestCounter++
@@ -331,7 +438,7 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 						// 			return
 						// 		}
 
-						// 		burnResult, err := utils.BurnToken(amount, token, memoText)
+						// 		burnResult, err := utils.BurnToken(amount, token, memoText, nil)
 						// 		if err != nil {
 						// 			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Error burning tokens: %v", err))
 						// 		} else {
@@ -371,6 +478,29 @@ func createAutoHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInf
 	return autoHarvestForm
 }
 
+// runTWAPOrder runs a TWAP order to completion and refreshes the wallet afterwards
+func runTWAPOrder(app *tview.Application, moduleUI *ModuleUI, walletInfoView *tview.TextView, order *utils.TWAPOrder) {
+	if err := order.Run(moduleUI.LogView); err != nil {
+		utils.LogMessage(moduleUI.LogView, "TWAP order error: "+err.Error())
+	}
+	UpdateWalletInfo(app, walletInfoView)
+}
+
+// addSwapLimitFields adds the slippage and price impact limit inputs to a harvest form
+func addSwapLimitFields(form *tview.Form, slippageBps *int, maxPriceImpactPct *float64) {
+	form.AddInputField("Slippage (bps)", strconv.Itoa(*slippageBps), 10, tview.InputFieldInteger, func(text string) {
+		if val, err := strconv.Atoi(text); err == nil && val > 0 {
+			*slippageBps = val
+		}
+	})
+
+	form.AddInputField("Max Price Impact (%, 0 = no limit)", strconv.FormatFloat(*maxPriceImpactPct, 'f', -1, 64), 10, tview.InputFieldFloat, func(text string) {
+		if val, err := strconv.ParseFloat(text, 64); err == nil && val >= 0 {
+			*maxPriceImpactPct = val
+		}
+	})
+}
+
 func createManualHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletInfoView *tview.TextView) *tview.Form {
 	// Create Manual Harvest form
 	manualHarvestForm := tview.NewForm()
@@ -378,11 +508,22 @@ func createManualHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletI
 		SetTitle("Manual Harvest").
 		SetTitleAlign(tview.AlignLeft)
 
+	// Swap limits default to the saved configuration
+	slippageBps := utils.DefaultSlippageBps
+	maxPriceImpactPct := utils.DefaultMaxPriceImpactPct
+	if config, err := utils.ReadSolXENConfigFile(); err == nil {
+		slippageBps = config.SlippageBps
+		maxPriceImpactPct = config.MaxPriceImpactPct
+	}
+
 	var tokenAmountText *tview.TextView
 	var selectedToken string
 	solAmount := "0.001"
-	tokenIndex := 0 // Default to solXEN
-	selectedToken = tokenOptions[tokenIndex]
+	tokenOptions := utils.GetEnabledTokenSymbols()
+	tokenIndex := 0 // Default to the first registered token
+	if len(tokenOptions) > 0 {
+		selectedToken = tokenOptions[tokenIndex]
+	}
 
 	updateTokenAmount := func() {
 		go func() {
@@ -417,15 +558,19 @@ func createManualHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletI
 	})
 
 	// Create the token dropdown
+	selectToken := func(text string, index int) {
+		selectedToken = text
+		updateTokenAmount()
+	}
 	tokenDropdown := tview.NewDropDown().
 		SetLabel("Token to Harvest").
-		SetOptions(tokenOptions, func(text string, index int) {
-			selectedToken = text
-			updateTokenAmount()
-		}).
+		SetOptions(tokenOptions, selectToken).
 		SetCurrentOption(tokenIndex)
 
 	manualHarvestForm.AddFormItem(tokenDropdown)
+	onTokensChanged(func(tokens []string) {
+		setTokenDropDownOptions(tokenDropdown, tokens, selectToken)
+	})
 
 	// 3. Token amount field
 	tokenAmountText = tview.NewTextView()
@@ -433,42 +578,79 @@ func createManualHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletI
 
 	manualHarvestForm.AddFormItem(tokenAmountText)
 
+	// 4. Slippage and price impact limit
+	addSwapLimitFields(manualHarvestForm, &slippageBps, &maxPriceImpactPct)
+
 	// Initial token amount calculation
 	updateTokenAmount()
 
-	// 3. Swap button
+	// Manual harvests show a simulation preview before signing
+	confirmTransaction := CreateTransactionConfirmer(app)
+
+	// 5. Swap button
 	manualHarvestForm.AddButton("Harvest", func() {
+		solAmount, selectedToken := solAmount, selectedToken
+		slippageBps, maxPriceImpactPct := slippageBps, maxPriceImpactPct
 
-		// Get SOL balance
-		solBalance, err := utils.GetSOLBalance(utils.GetGlobalPublicKey())
-		if err != nil {
-			utils.LogMessage(moduleUI.LogView, "Error getting SOL balance: "+err.Error())
+		if err := utils.CheckHarvestPayout(); err != nil {
+			utils.LogMessage(moduleUI.LogView, "Harvest disabled: "+err.Error())
 			return
 		}
 
-		// Check if the SOL balance is greater than 0.000006
-		if solBalance <= 0.000006 {
-			utils.LogMessage(moduleUI.LogView, "Insufficient SOL balance. Minimum required: 0.000006 SOL")
-			return
-		}
+		// Run in the background so the confirmation modal can be shown
+		go func() {
+			// Get SOL balance
+			solBalance, err := utils.GetSOLBalance(utils.GetGlobalPublicKey())
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error getting SOL balance: "+err.Error())
+				return
+			}
 
-		utils.LogMessage(moduleUI.LogView, fmt.Sprintf("SOL: %s for %s", solAmount, selectedToken))
+			// Check if the SOL balance is greater than 0.000006
+			if solBalance <= 0.000006 {
+				utils.LogMessage(moduleUI.LogView, "Insufficient SOL balance. Minimum required: 0.000006 SOL")
+				return
+			}
 
-		result, err := utils.ExchangeSolForToken(solAmount, selectedToken)
-		if err != nil {
-			// Handle error
-			utils.LogMessage(moduleUI.LogView, "Error: "+err.Error())
-		} else {
-			tokenAmountText.SetText("Amount(Est.): \n" + result + " " + selectedToken)
-			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("SOL: %s -> %s: %s successfully", solAmount, selectedToken, result))
+			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("SOL: %s for %s", solAmount, selectedToken))
 
-			// Update wallet info after 60 seconds
-			go func() {
-				time.Sleep(60 * time.Second)
-				UpdateWalletInfo(app, walletInfoView)
-			}()
-		}
+			// Fetch a fresh quote and let the user review it
+			quote, err := utils.GetSwapQuote(utils.SOL, selectedToken, solAmount, utils.SwapModeExactIn, slippageBps)
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error getting quote: "+err.Error())
+				return
+			}
+			if err := utils.CheckPriceImpact(quote, maxPriceImpactPct); err != nil {
+				utils.LogMessage(moduleUI.LogView, "Quote refused: "+err.Error())
+				return
+			}
+			if !ShowConfirmModal(app, utils.FormatQuote(quote), true) {
+				utils.LogMessage(moduleUI.LogView, "Harvest cancelled")
+				return
+			}
 
+			result, err := utils.ExchangeSolForToken(solAmount, selectedToken, utils.SwapOptions{
+				SlippageBps:       slippageBps,
+				MaxPriceImpactPct: maxPriceImpactPct,
+				Quote:             quote,
+				Confirm:           confirmTransaction,
+			})
+			if err != nil {
+				// Handle error
+				utils.LogMessage(moduleUI.LogView, "Error: "+err.Error())
+			} else {
+				app.QueueUpdateDraw(func() {
+					tokenAmountText.SetText("Amount(Received): \n" + strconv.FormatFloat(result.AmountOut, 'f', -1, 64) + " " + selectedToken)
+				})
+				utils.LogMessage(moduleUI.LogView, fmt.Sprintf("%s successfully", result))
+
+				// Update wallet info after 60 seconds
+				go func() {
+					time.Sleep(60 * time.Second)
+					UpdateWalletInfo(app, walletInfoView)
+				}()
+			}
+		}()
 	})
 
 	// Add Burn Memo input field
@@ -510,7 +692,7 @@ func createManualHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletI
 
 	// 	utils.LogMessage(moduleUI.LogView, fmt.Sprintf("SOL: %s for %s", solAmount, selectedToken))
 
-	// 	result, err := utils.ExchangeSolForToken(solAmount, selectedToken)
+	// 	result, err := utils.ExchangeSolForToken(solAmount, selectedToken, utils.SwapOptions{Confirm: confirmTransaction})
 	// 	if err != nil {
 	// 		utils.LogMessage(moduleUI.LogView, "Error: "+err.Error())
 	// 	} else {
@@ -538,7 +720,7 @@ func createManualHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletI
 	// 			}
 	// 			memoText := string(jsonData)
 
-	// 			burnResult, err := utils.BurnToken(tokenAmount, selectedToken, memoText)
+	// 			burnResult, err := utils.BurnToken(tokenAmount, selectedToken, memoText, confirmTransaction)
 	// 			if err != nil {
 	// 				utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Error burning tokens: %v", err))
 	// 			} else {
@@ -559,3 +741,295 @@ func createManualHarvestForm(app *tview.Application, moduleUI *ModuleUI, walletI
 
 	return manualHarvestForm
 }
+
+func createSwapForm(app *tview.Application, moduleUI *ModuleUI, walletInfoView *tview.TextView) *tview.Form {
+	// Create Swap form
+	swapForm := tview.NewForm()
+	swapForm.SetBorder(true).
+		SetTitle("Swap").
+		SetTitleAlign(tview.AlignLeft)
+
+	// Swap limits default to the saved configuration
+	slippageBps := utils.DefaultSlippageBps
+	maxPriceImpactPct := utils.DefaultMaxPriceImpactPct
+	if config, err := utils.ReadSolXENConfigFile(); err == nil {
+		slippageBps = config.SlippageBps
+		maxPriceImpactPct = config.MaxPriceImpactPct
+	}
+
+	swapTokenOptions := func(tokens []string) []string {
+		return append([]string{utils.SOL}, tokens...)
+	}
+	swapModeOptions := []string{utils.SwapModeExactIn, utils.SwapModeExactOut}
+
+	// Default to taking profit: first token back to SOL
+	tokenOptions := utils.GetEnabledTokenSymbols()
+	inputToken := utils.SOL
+	inputIndex := 0
+	if len(tokenOptions) > 0 {
+		inputToken = tokenOptions[0]
+		inputIndex = 1
+	}
+	outputToken := utils.SOL
+	swapMode := utils.SwapModeExactIn
+	amount := ""
+
+	// 1. Dropdowns for the token pair
+	selectInputToken := func(option string, index int) {
+		inputToken = option
+	}
+	selectOutputToken := func(option string, index int) {
+		outputToken = option
+	}
+	swapForm.AddDropDown("From", swapTokenOptions(tokenOptions), inputIndex, selectInputToken)
+	swapForm.AddDropDown("To", swapTokenOptions(tokenOptions), 0, selectOutputToken)
+	inputDropDown := swapForm.GetFormItemByLabel("From").(*tview.DropDown)
+	outputDropDown := swapForm.GetFormItemByLabel("To").(*tview.DropDown)
+	onTokensChanged(func(tokens []string) {
+		setTokenDropDownOptions(inputDropDown, swapTokenOptions(tokens), selectInputToken)
+		setTokenDropDownOptions(outputDropDown, swapTokenOptions(tokens), selectOutputToken)
+	})
+
+	// 2. Dropdown for the swap mode
+	swapForm.AddDropDown("Mode", swapModeOptions, 0, func(option string, index int) {
+		swapMode = option
+	})
+
+	// 3. Amount input field, paid for ExactIn and received for ExactOut
+	swapForm.AddInputField("Amount", amount, 16, tview.InputFieldFloat, func(text string) {
+		amount = text
+	})
+
+	// 4. Slippage and price impact limit
+	addSwapLimitFields(swapForm, &slippageBps, &maxPriceImpactPct)
+
+	// 5. Result field
+	swapResultText := tview.NewTextView()
+	swapResultText.SetText("Amount(Received): \n-")
+	swapForm.AddFormItem(swapResultText)
+
+	// Swaps show a simulation preview before signing
+	confirmTransaction := CreateTransactionConfirmer(app)
+
+	// 6. Swap button
+	swapForm.AddButton("Swap", func() {
+		inputToken, outputToken, swapMode, amount := inputToken, outputToken, swapMode, amount
+		slippageBps, maxPriceImpactPct := slippageBps, maxPriceImpactPct
+
+		if inputToken == outputToken {
+			utils.LogMessage(moduleUI.LogView, "Select two different tokens to swap")
+			return
+		}
+		amountFloat, err := strconv.ParseFloat(amount, 64)
+		if err != nil || amountFloat <= 0 {
+			utils.LogMessage(moduleUI.LogView, "Enter a valid amount to swap")
+			return
+		}
+
+		// Run in the background so the confirmation modal can be shown
+		go func() {
+			// Get SOL balance, it pays the fees of every swap
+			solBalance, err := utils.GetSOLBalance(utils.GetGlobalPublicKey())
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error getting SOL balance: "+err.Error())
+				return
+			}
+			if solBalance <= 0.000006 {
+				utils.LogMessage(moduleUI.LogView, "Insufficient SOL balance. Minimum required: 0.000006 SOL")
+				return
+			}
+
+			// In ExactIn mode the amount paid is known up front
+			if swapMode == utils.SwapModeExactIn {
+				inputBalance := solBalance
+				if inputToken != utils.SOL {
+					inputBalance = 0
+					balances, err := utils.GetWalletTokenBalances(utils.GetGlobalPublicKey())
+					if err != nil {
+						utils.LogMessage(moduleUI.LogView, "Error getting wallet balances: "+err.Error())
+						return
+					}
+					for _, balance := range balances {
+						if balance.Symbol == inputToken {
+							inputBalance = balance.Balance
+							break
+						}
+					}
+				}
+				if inputBalance < amountFloat {
+					utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Insufficient %s balance %f. Required: %s", inputToken, inputBalance, amount))
+					return
+				}
+			}
+
+			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Swap %s: %s %s -> %s", swapMode, amount, inputToken, outputToken))
+
+			// Fetch a fresh quote and let the user review it
+			quote, err := utils.GetSwapQuote(inputToken, outputToken, amount, swapMode, slippageBps)
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error getting quote: "+err.Error())
+				return
+			}
+			if err := utils.CheckPriceImpact(quote, maxPriceImpactPct); err != nil {
+				utils.LogMessage(moduleUI.LogView, "Quote refused: "+err.Error())
+				return
+			}
+			if !ShowConfirmModal(app, utils.FormatQuote(quote), true) {
+				utils.LogMessage(moduleUI.LogView, "Swap cancelled")
+				return
+			}
+
+			result, err := utils.Swap(inputToken, outputToken, amount, swapMode, utils.SwapOptions{
+				SlippageBps:       slippageBps,
+				MaxPriceImpactPct: maxPriceImpactPct,
+				Quote:             quote,
+				Confirm:           confirmTransaction,
+			})
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error: "+err.Error())
+				return
+			}
+
+			app.QueueUpdateDraw(func() {
+				swapResultText.SetText("Amount(Received): \n" + strconv.FormatFloat(result.AmountOut, 'f', -1, 64) + " " + outputToken)
+			})
+			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("%s successfully", result))
+
+			// Update wallet info after 60 seconds
+			go func() {
+				time.Sleep(60 * time.Second)
+				UpdateWalletInfo(app, walletInfoView)
+			}()
+		}()
+	})
+
+	return swapForm
+}
+
+func createTokenRegistryForm(app *tview.Application, moduleUI *ModuleUI, walletInfoView *tview.TextView) *tview.Form {
+	// Create Tokens form
+	tokenRegistryForm := tview.NewForm()
+	tokenRegistryForm.SetBorder(true).
+		SetTitle("Tokens").
+		SetTitleAlign(tview.AlignLeft)
+
+	mintAddress := ""
+	symbol := ""
+
+	// 1. Mint address and symbol of the SPL token
+	tokenRegistryForm.AddInputField("Mint Address", mintAddress, 44, nil, func(text string) {
+		mintAddress = text
+	})
+	tokenRegistryForm.AddInputField("Symbol", symbol, 12, nil, func(text string) {
+		symbol = text
+	})
+
+	// 2. Registered tokens with their state, to enable or disable one
+	tokenDropDown := tview.NewDropDown().SetLabel("Registered")
+	tokenDropDown.SetOptions(tokenRegistryOptions(), nil)
+	tokenDropDown.SetCurrentOption(0)
+	tokenRegistryForm.AddFormItem(tokenDropDown)
+
+	refreshRegistered := func() {
+		app.QueueUpdateDraw(func() {
+			index, _ := tokenDropDown.GetCurrentOption()
+			tokenDropDown.SetOptions(tokenRegistryOptions(), nil)
+			if index < 0 || index >= tokenDropDown.GetOptionCount() {
+				index = 0
+			}
+			tokenDropDown.SetCurrentOption(index)
+		})
+	}
+
+	// 3. Add Token button, the mint is validated on-chain
+	tokenRegistryForm.AddButton("Add Token", func() {
+		mintAddress, symbol := mintAddress, symbol
+
+		go func() {
+			token, err := utils.AddToken(mintAddress, symbol)
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error adding token: "+err.Error())
+				return
+			}
+			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Token %s added (%s, %d decimals)", token.Symbol, token.Mint, token.Decimals))
+
+			utils.RefreshPrices()
+			notifyTokensChanged(app)
+			refreshRegistered()
+			UpdateWalletInfo(app, walletInfoView)
+		}()
+	})
+
+	// 4. Enable/Disable button for the selected token
+	tokenRegistryForm.AddButton("Enable/Disable", func() {
+		index, _ := tokenDropDown.GetCurrentOption()
+		tokens := utils.GetTokens()
+		if index < 0 || index >= len(tokens) {
+			utils.LogMessage(moduleUI.LogView, "No token selected")
+			return
+		}
+		token := tokens[index]
+
+		go func() {
+			if err := utils.SetTokenEnabled(token.Symbol, !token.Enabled); err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error changing token: "+err.Error())
+				return
+			}
+			if token.Enabled {
+				utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Token %s disabled", token.Symbol))
+			} else {
+				utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Token %s enabled", token.Symbol))
+			}
+
+			utils.RefreshPrices()
+			notifyTokensChanged(app)
+			refreshRegistered()
+			UpdateWalletInfo(app, walletInfoView)
+		}()
+	})
+
+	return tokenRegistryForm
+}
+
+// tokenRegistryOptions lists all registered tokens in registry order with their state
+func tokenRegistryOptions() []string {
+	var options []string
+	for _, token := range utils.GetTokens() {
+		state := "disabled"
+		if token.Enabled {
+			state = "enabled"
+		}
+		options = append(options, fmt.Sprintf("%s (%s)", token.Symbol, state))
+	}
+	return options
+}
+
+// onTokensChanged registers a listener for changes of the enabled tokens
+func onTokensChanged(listener func(tokens []string)) {
+	tokenListeners = append(tokenListeners, listener)
+}
+
+// notifyTokensChanged passes the enabled tokens to all listeners on the UI goroutine
+func notifyTokensChanged(app *tview.Application) {
+	tokens := utils.GetEnabledTokenSymbols()
+	app.QueueUpdateDraw(func() {
+		for _, listener := range tokenListeners {
+			listener(tokens)
+		}
+	})
+}
+
+// setTokenDropDownOptions replaces the options of a token dropdown and keeps its selection
+func setTokenDropDownOptions(dropDown *tview.DropDown, options []string, selected func(option string, index int)) {
+	_, current := dropDown.GetCurrentOption()
+	dropDown.SetOptions(options, selected)
+
+	index := 0
+	for i, option := range options {
+		if option == current {
+			index = i
+			break
+		}
+	}
+	dropDown.SetCurrentOption(index)
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"fmt"
 	"xoon/utils"
 
 	"github.com/rivo/tview"
@@ -84,17 +85,22 @@ func CreateWalletUI(app *tview.Application) ModuleUI {
 		})
 	manageWalletForm.SetBorder(true).SetTitle("Manage Wallet")
 
+	payoutForm := createPayoutForm(moduleUI.LogView)
+
 	// Create a flex layout for vertical arrangement
 	walletFlex := tview.NewFlex().SetDirection(tview.FlexRow)
 
 	if utils.GetGlobalPublicKey() != "" {
 		// If GLOBAL_PUBLIC_KEY is not empty, only add manageWalletForm
-		walletFlex.AddItem(manageWalletForm, 0, 1, true)
+		walletFlex.
+			AddItem(manageWalletForm, 0, 1, true).
+			AddItem(payoutForm, 0, 1, false)
 	} else {
 		// If GLOBAL_PUBLIC_KEY is empty, add both forms
 		walletFlex.
 			AddItem(createWalletForm, 0, 1, true).
-			AddItem(manageWalletForm, 0, 1, false)
+			AddItem(manageWalletForm, 0, 1, false).
+			AddItem(payoutForm, 0, 1, false)
 	}
 
 	// Add the flex layout to moduleUI
@@ -103,6 +109,57 @@ func CreateWalletUI(app *tview.Application) ModuleUI {
 	return moduleUI
 }
 
+// createPayoutForm edits the coin unMineable pays out and its address.
+// Harvesting and the solXEN conversions are off for coins other than SOL.
+func createPayoutForm(logView *tview.TextView) *tview.Form {
+	config, err := utils.ReadMiningConfigFile()
+	if err != nil {
+		utils.LogMessage(logView, "Error reading mining config: "+err.Error())
+		config = utils.MiningConfig{PayoutCoin: utils.SOL, ReferralCode: utils.DefaultReferralCode}
+	}
+
+	payoutCoin := config.PayoutCoin
+	payoutAddress := config.PayoutAddress
+
+	coinIndex := 0
+	for i, coin := range utils.PayoutCoins {
+		if coin == payoutCoin {
+			coinIndex = i
+			break
+		}
+	}
+
+	payoutForm := tview.NewForm()
+	payoutForm.
+		AddDropDown("Payout Coin", utils.PayoutCoins, coinIndex, func(option string, index int) {
+			payoutCoin = option
+		}).
+		AddInputField("Payout Address (empty = wallet for SOL)", payoutAddress, 50, nil, func(text string) {
+			payoutAddress = text
+		}).
+		AddButton("Save Payout", func() {
+			config, err := utils.ReadMiningConfigFile()
+			if err != nil {
+				utils.LogMessage(logView, "Error reading mining config: "+err.Error())
+				return
+			}
+			config.PayoutCoin = payoutCoin
+			config.PayoutAddress = payoutAddress
+			if err := utils.WriteMiningConfigFile(config); err != nil {
+				utils.LogMessage(logView, "Error saving payout: "+err.Error())
+				return
+			}
+
+			utils.LogMessage(logView, fmt.Sprintf("Payout set to %s, restart the miners to apply it", payoutCoin))
+			if payoutCoin != utils.SOL {
+				utils.LogMessage(logView, "Harvesting and solXEN conversions are disabled while the payout isn't SOL")
+			}
+		})
+	payoutForm.SetBorder(true).SetTitle("Mining Payout")
+
+	return payoutForm
+}
+
 func CreateWalletConfigFlex(app *tview.Application, logView *tview.TextView) *tview.Flex {
 	configFlex := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
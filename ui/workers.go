@@ -0,0 +1,216 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"xoon/utils"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func CreateUnmineableWorkersUI(app *tview.Application) ModuleUI {
+	var moduleUI = CreateModuleUI(UNMINEABLE_WORKERS_STRING, app)
+
+	workersTable := tview.NewTable().
+		SetBorders(false).
+		SetFixed(1, 0)
+	workersTable.SetBorder(true).
+		SetTitle("Workers").
+		SetTitleAlign(tview.AlignLeft)
+
+	updateWorkers := func() {
+		if utils.GetGlobalPublicKey() == "" {
+			return
+		}
+
+		go func() {
+			coin, address, err := utils.GetPayout()
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Invalid payout: "+err.Error())
+				return
+			}
+
+			workers, err := utils.GetUnmineableWorkers(address, coin)
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error fetching workers: "+err.Error())
+				return
+			}
+
+			online := 0
+			for _, worker := range workers {
+				if worker.Online {
+					online++
+				}
+			}
+
+			app.QueueUpdateDraw(func() {
+				fillWorkersTable(workersTable, workers)
+			})
+			utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Workers: %d online, %d offline", online, len(workers)-online))
+		}()
+	}
+
+	payoutsTable := tview.NewTable().
+		SetBorders(false).
+		SetFixed(1, 0)
+	payoutsTable.SetBorder(true).
+		SetTitle("Payouts").
+		SetTitleAlign(tview.AlignLeft)
+
+	// Show the payouts of the last reconciliation until the next one
+	if ledger, err := utils.LoadPayoutLedger(); err != nil {
+		utils.LogMessage(moduleUI.LogView, "Error loading payout ledger: "+err.Error())
+	} else if ledger != nil {
+		fillPayoutsTable(payoutsTable, ledger)
+	}
+
+	reconcilePayouts := func() {
+		if utils.GetGlobalPublicKey() == "" {
+			return
+		}
+
+		utils.LogMessage(moduleUI.LogView, "Reconciling payouts with their transactions...")
+		go func() {
+			ledger, err := utils.ReconcilePayouts()
+			if err != nil {
+				utils.LogMessage(moduleUI.LogView, "Error reconciling payouts: "+err.Error())
+				return
+			}
+
+			app.QueueUpdateDraw(func() {
+				fillPayoutsTable(payoutsTable, ledger)
+			})
+			utils.LogMessage(moduleUI.LogView, "Payouts: "+ledger.String())
+			for _, record := range ledger.Flagged() {
+				utils.LogMessage(moduleUI.LogView, fmt.Sprintf("Payout of %s %s on %s is %s: %s",
+					strconv.FormatFloat(record.Amount, 'f', -1, 64), record.Coin,
+					record.Date.Format("2006-01-02"), record.Reconciliation, record.Note))
+			}
+		}()
+	}
+
+	form := tview.NewForm().
+		AddButton("Refresh", updateWorkers).
+		AddButton("Reconcile Payouts", reconcilePayouts)
+
+	contentFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(workersTable, 0, 1, false).
+		AddItem(payoutsTable, 0, 1, false).
+		AddItem(form, 3, 0, true)
+
+	moduleUI.ConfigFlex.AddItem(contentFlex, 0, 1, true)
+
+	// Initial update
+	updateWorkers()
+
+	// Set up a ticker to update every 10 minutes
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		for range ticker.C {
+			updateWorkers()
+		}
+	}()
+
+	return moduleUI
+}
+
+func CreateUnmineableWorkersConfigFlex(app *tview.Application, logView *tview.TextView) *tview.Flex {
+	configFlex := tview.NewFlex().
+		SetDirection(tview.FlexColumn)
+
+	configFlex.SetBorder(true).SetTitle(UNMINEABLE_WORKERS_STRING)
+	return configFlex
+}
+
+func fillWorkersTable(table *tview.Table, workers []utils.UnmineableWorker) {
+	table.Clear()
+
+	headers := []string{"Worker", "Algorithm", "State", "Reported", "Calculated", "Last Share"}
+	for column, header := range headers {
+		table.SetCell(0, column, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for i, worker := range workers {
+		state, stateColor := "offline", tcell.ColorRed
+		if worker.Online {
+			state, stateColor = "online", tcell.ColorGreen
+		}
+
+		lastShare := "-"
+		if !worker.LastShare.IsZero() {
+			lastShare = worker.LastShare.Format("2006-01-02 15:04") + " (" + time.Since(worker.LastShare).Round(time.Minute).String() + " ago)"
+		}
+
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(worker.Name).SetExpansion(1))
+		table.SetCell(row, 1, tview.NewTableCell(worker.Algorithm).SetExpansion(1))
+		table.SetCell(row, 2, tview.NewTableCell(state).SetTextColor(stateColor).SetExpansion(1))
+		table.SetCell(row, 3, tview.NewTableCell(formatHashrate(worker.ReportedHash)).SetExpansion(1))
+		table.SetCell(row, 4, tview.NewTableCell(formatHashrate(worker.CalculatedHash)).SetExpansion(1))
+		table.SetCell(row, 5, tview.NewTableCell(lastShare).SetExpansion(1))
+	}
+
+	if len(workers) == 0 {
+		table.SetCell(1, 0, tview.NewTableCell("No workers found"))
+	}
+}
+
+func fillPayoutsTable(table *tview.Table, ledger *utils.PayoutLedger) {
+	table.Clear()
+	table.SetTitle(fmt.Sprintf("Payouts (%s SOL paid, %s SOL received)",
+		strconv.FormatFloat(ledger.TotalPaid, 'f', 6, 64),
+		strconv.FormatFloat(ledger.TotalReceived, 'f', 6, 64)))
+
+	headers := []string{"Date", "Amount", "Received", "Reconciliation", "Transaction"}
+	for column, header := range headers {
+		table.SetCell(0, column, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for i, record := range ledger.Payouts {
+		color := tcell.ColorWhite
+		switch record.Reconciliation {
+		case utils.PayoutMatched:
+			color = tcell.ColorGreen
+		case utils.PayoutMismatched, utils.PayoutMissing:
+			color = tcell.ColorRed
+		case utils.PayoutUnchecked:
+			color = tcell.ColorYellow
+		}
+
+		tx := "-"
+		if record.TxHash != "" {
+			tx = record.TxHash[:min(len(record.TxHash), 16)] + "..."
+		}
+
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(record.Date.Format("2006-01-02 15:04")).SetExpansion(1))
+		table.SetCell(row, 1, tview.NewTableCell(strconv.FormatFloat(record.Amount, 'f', 6, 64)+" "+record.Coin).SetExpansion(1))
+		table.SetCell(row, 2, tview.NewTableCell(strconv.FormatFloat(record.ReceivedSOL, 'f', 6, 64)+" SOL").SetExpansion(1))
+		table.SetCell(row, 3, tview.NewTableCell(string(record.Reconciliation)).SetTextColor(color).SetExpansion(1))
+		table.SetCell(row, 4, tview.NewTableCell(tx).SetExpansion(1))
+	}
+
+	if len(ledger.Payouts) == 0 {
+		table.SetCell(1, 0, tview.NewTableCell("No payouts found"))
+	}
+}
+
+// formatHashrate formats a hashrate in H/s with a unit prefix
+func formatHashrate(hashrate float64) string {
+	units := []string{"H/s", "kH/s", "MH/s", "GH/s", "TH/s"}
+	unit := 0
+	for hashrate >= 1000 && unit < len(units)-1 {
+		hashrate /= 1000
+		unit++
+	}
+	return fmt.Sprintf("%.2f %s", hashrate, units[unit])
+}
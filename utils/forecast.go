@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reaching the payout threshold slower than this is worth a warning
+const slowPayoutThreshold = 30 * 24 * time.Hour
+
+// Payout ETAs are capped, a tiny reward rate would overflow a time.Duration
+const maxPayoutETA = 100 * 365 * 24 * time.Hour
+
+// EarningsForecast projects the mining rewards from the recent reward rate
+type EarningsForecast struct {
+	Coin string
+	// Reward rate in coin per day and the rewards it was averaged over
+	DailyRate  float64
+	RateSource string
+	Daily      float64
+	Weekly     float64
+	Monthly    float64
+	// solXEN at the current price, only for a SOL payout
+	HasSolXEN     bool
+	DailySolXEN   float64
+	WeeklySolXEN  float64
+	MonthlySolXEN float64
+	// Time until the pending balance reaches the payout threshold
+	PayoutDue    bool
+	HasPayoutETA bool
+	TimeToPayout time.Duration
+	PayoutETA    time.Time
+	AutoPay      bool
+	Warnings     []string
+}
+
+// GetEarningsForecast projects the rewards and the next auto-payout of the
+// account from its rewards over the last 7 days, or 24h or 30d if missing
+func GetEarningsForecast(info *UnmineableInfo) (*EarningsForecast, error) {
+	if info == nil {
+		return nil, errors.New("no mining stats")
+	}
+
+	forecast := &EarningsForecast{Coin: info.Coin, AutoPay: info.AutoPay, RateSource: "no"}
+
+	// 1. Reward rate, the 7 day average smooths out luck
+	rates := []struct {
+		source string
+		amount string
+		days   float64
+	}{
+		{"7d", info.Past7d, 7},
+		{"24h", info.Past24h, 1},
+		{"30d", info.Past30d, 30},
+	}
+	for _, rate := range rates {
+		amount, err := strconv.ParseFloat(rate.amount, 64)
+		if err == nil && amount > 0 {
+			forecast.DailyRate = amount / rate.days
+			forecast.RateSource = rate.source
+			break
+		}
+	}
+
+	forecast.Daily = forecast.DailyRate
+	forecast.Weekly = forecast.DailyRate * 7
+	forecast.Monthly = forecast.DailyRate * 30
+
+	// 2. solXEN at the current price
+	if forecast.Coin == SOL {
+		if price, err := GetTokenPrice(SolXEN); err == nil {
+			tokensPerSOL := price.TokensPerSOL()
+			forecast.HasSolXEN = true
+			forecast.DailySolXEN = forecast.Daily * tokensPerSOL
+			forecast.WeeklySolXEN = forecast.Weekly * tokensPerSOL
+			forecast.MonthlySolXEN = forecast.Monthly * tokensPerSOL
+		}
+	}
+
+	// 3. Time until the payout threshold
+	balance, balanceErr := strconv.ParseFloat(info.Balance, 64)
+	threshold, thresholdErr := strconv.ParseFloat(info.PaymentThreshold, 64)
+	if balanceErr == nil && thresholdErr == nil && threshold > 0 {
+		if balance >= threshold {
+			forecast.PayoutDue = true
+		} else if forecast.DailyRate > 0 {
+			days := (threshold - balance) / forecast.DailyRate
+			forecast.HasPayoutETA = true
+			forecast.TimeToPayout = maxPayoutETA
+			if days < maxPayoutETA.Hours()/24 {
+				forecast.TimeToPayout = time.Duration(days * float64(24*time.Hour))
+			}
+			forecast.PayoutETA = time.Now().Add(forecast.TimeToPayout)
+		}
+	}
+
+	// 4. Warnings
+	if !forecast.AutoPay {
+		forecast.Warnings = append(forecast.Warnings, "auto-pay is off, payouts must be requested on unMineable")
+	}
+	if forecast.DailyRate == 0 {
+		forecast.Warnings = append(forecast.Warnings, "no rewards in the last 30 days")
+	} else if forecast.HasPayoutETA && forecast.TimeToPayout > slowPayoutThreshold {
+		forecast.Warnings = append(forecast.Warnings, fmt.Sprintf("the payout threshold takes %d days to reach", int(forecast.TimeToPayout.Hours()/24)))
+	}
+
+	return forecast, nil
+}
+
+// String formats the forecast for the dashboard
+func (f *EarningsForecast) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Forecast (%s avg): day %s | week %s | month %s %s",
+		f.RateSource, formatForecastAmount(f.Daily), formatForecastAmount(f.Weekly), formatForecastAmount(f.Monthly), f.Coin))
+	if f.HasSolXEN {
+		sb.WriteString(fmt.Sprintf(" (%.0f | %.0f | %.0f solXEN)", f.DailySolXEN, f.WeeklySolXEN, f.MonthlySolXEN))
+	}
+
+	switch {
+	case f.PayoutDue:
+		sb.WriteString(" | Payout: threshold reached")
+	case f.HasPayoutETA && f.TimeToPayout >= maxPayoutETA:
+		sb.WriteString(" | Payout in over 100 years")
+	case f.HasPayoutETA:
+		sb.WriteString(fmt.Sprintf(" | Payout in %s (%s)", formatPayoutETA(f.TimeToPayout), f.PayoutETA.Format("2006-01-02")))
+	default:
+		sb.WriteString(" | Payout: unknown")
+	}
+
+	if len(f.Warnings) > 0 {
+		sb.WriteString("\n[red]Warning: " + strings.Join(f.Warnings, "; ") + "[-]")
+	}
+
+	return sb.String()
+}
+
+func formatForecastAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 6, 64)
+}
+
+// formatPayoutETA formats a duration in days and hours
+func formatPayoutETA(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetEarningsForecast(t *testing.T) {
+	tests := []struct {
+		name        string
+		info        UnmineableInfo
+		wantDaily   float64
+		wantDue     bool
+		wantETA     time.Duration
+		wantPayout  string
+		wantWarning string
+	}{
+		{
+			name:       "7 day average",
+			info:       UnmineableInfo{Coin: "XMR", AutoPay: true, Balance: "0.01", PaymentThreshold: "0.1", Past7d: "0.07"},
+			wantDaily:  0.01,
+			wantETA:    9 * 24 * time.Hour,
+			wantPayout: "Payout in 9d 0h",
+		},
+		{
+			name:       "24h without a 7 day figure",
+			info:       UnmineableInfo{Coin: "XMR", AutoPay: true, Balance: "0", PaymentThreshold: "0.1", Past24h: "0.1", Past7d: "0"},
+			wantDaily:  0.1,
+			wantETA:    24 * time.Hour,
+			wantPayout: "Payout in 1d 0h",
+		},
+		{
+			name:       "threshold reached",
+			info:       UnmineableInfo{Coin: "XMR", AutoPay: true, Balance: "0.2", PaymentThreshold: "0.1", Past7d: "0.07"},
+			wantDaily:  0.01,
+			wantDue:    true,
+			wantPayout: "threshold reached",
+		},
+		{
+			// 1e-15 XMR a day takes far longer than a time.Duration holds
+			name:        "tiny reward rate",
+			info:        UnmineableInfo{Coin: "XMR", AutoPay: true, Balance: "0", PaymentThreshold: "0.1", Past24h: "0.000000000000001"},
+			wantDaily:   1e-15,
+			wantETA:     maxPayoutETA,
+			wantPayout:  "over 100 years",
+			wantWarning: "payout threshold takes",
+		},
+		{
+			name:        "no rewards",
+			info:        UnmineableInfo{Coin: "XMR", AutoPay: false, Balance: "0", PaymentThreshold: "0.1"},
+			wantPayout:  "Payout: unknown",
+			wantWarning: "no rewards",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast, err := GetEarningsForecast(&tt.info)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if forecast.DailyRate != tt.wantDaily {
+				t.Errorf("DailyRate = %v, want %v", forecast.DailyRate, tt.wantDaily)
+			}
+			if forecast.PayoutDue != tt.wantDue {
+				t.Errorf("PayoutDue = %v, want %v", forecast.PayoutDue, tt.wantDue)
+			}
+			if forecast.TimeToPayout < tt.wantETA-time.Second || forecast.TimeToPayout > tt.wantETA+time.Second {
+				t.Errorf("TimeToPayout = %v, want %v", forecast.TimeToPayout, tt.wantETA)
+			}
+			text := forecast.String()
+			if !strings.Contains(text, tt.wantPayout) {
+				t.Errorf("String() = %q, want it to contain %q", text, tt.wantPayout)
+			}
+			if tt.wantWarning != "" && !strings.Contains(strings.Join(forecast.Warnings, "; "), tt.wantWarning) {
+				t.Errorf("Warnings = %v, want one containing %q", forecast.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxDropWindowHours is the longest drop window, the price history is kept this long
+const MaxDropWindowHours = int(priceHistoryWindow / time.Hour)
+
+// CheckHarvestConditions checks the optional price conditions of an auto
+// harvest. The returned error says why the harvest should be skipped.
+func CheckHarvestConditions(config SolXENConfig) error {
+	if config.MaxBuyPriceSOL <= 0 && config.MinDropPct <= 0 {
+		return nil
+	}
+
+	price, err := GetTokenPrice(config.TokenToHarvest)
+	if err != nil {
+		return fmt.Errorf("%s price unknown: %v", config.TokenToHarvest, err)
+	}
+	if price.Stale {
+		return fmt.Errorf("%s price is stale since %s", config.TokenToHarvest, price.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+
+	// 1. Only buy below a price
+	if config.MaxBuyPriceSOL > 0 && price.PriceSOL >= config.MaxBuyPriceSOL {
+		return fmt.Errorf("%s price %.12f SOL is not below %.12f SOL", config.TokenToHarvest, price.PriceSOL, config.MaxBuyPriceSOL)
+	}
+
+	// 2. Only buy after a drop over the window
+	if config.MinDropPct > 0 {
+		windowHours := config.DropWindowHours
+		if windowHours <= 0 {
+			windowHours = 24
+		}
+		window := time.Duration(windowHours) * time.Hour
+
+		history := GetPriceHistory(config.TokenToHarvest, window)
+		// The oldest point has to be close to the start of the window
+		if len(history) == 0 || time.Since(history[0].Time) < window-priceUpdateInterval {
+			return fmt.Errorf("not enough %s price history for the last %dh", config.TokenToHarvest, windowHours)
+		}
+
+		startPrice := history[0].PriceSOL
+		dropPct := (startPrice - price.PriceSOL) / startPrice * 100
+		if dropPct < config.MinDropPct {
+			return fmt.Errorf("%s price changed %+.2f%% over the last %dh, waiting for a %.2f%% drop", config.TokenToHarvest, -dropPct, windowHours, config.MinDropPct)
+		}
+	}
+
+	return nil
+}
+
+// CheckHarvestPayout checks that mining pays out in SOL. Harvests swap the
+// mined SOL, so they are off while another coin is mined.
+func CheckHarvestPayout() error {
+	config, err := ReadMiningConfigFile()
+	if err != nil {
+		return fmt.Errorf("failed to read mining config: %v", err)
+	}
+	if config.PayoutCoin != SOL {
+		return fmt.Errorf("harvesting needs a SOL payout, mining pays out in %s", config.PayoutCoin)
+	}
+	return nil
+}
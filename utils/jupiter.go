@@ -8,8 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,15 +23,24 @@ import (
 )
 
 const (
+	SOL             = "SOL"
 	SolXEN          = "solXEN"
 	OGSolXEN        = "OG solXEN"
 	xencat          = "xencat"
 	PV              = "PV"
 	ORE             = "ORE"
-	JupiterQuoteURL = "https://quote-api.jup.ag/v6/quote"
-	JupiterSwapURL  = "https://quote-api.jup.ag/v6/swap"
 	JupiterPriceURL = "https://api.jup.ag/price/v2"
 	SOLMint         = "So11111111111111111111111111111111111111112"
+
+	// Jupiter Swap API without an API key, and the one for keyed requests
+	DefaultJupiterBaseURL = "https://lite-api.jup.ag/swap/v1"
+	KeyedJupiterBaseURL   = "https://api.jup.ag/swap/v1"
+)
+
+// Jupiter swap modes: ExactIn fixes the input amount, ExactOut the output amount
+const (
+	SwapModeExactIn  = "ExactIn"
+	SwapModeExactOut = "ExactOut"
 )
 
 type RoutePlanItem struct {
@@ -66,266 +78,433 @@ type SwapRequest struct {
 	PrioritizationFeeLamports string        `json:"prioritizationFeeLamports"`
 }
 
+// SwapOptions controls quoting and confirmation of a swap
+type SwapOptions struct {
+	SlippageBps       int
+	MaxPriceImpactPct float64
+	// Quote already shown to the user, used for the first attempt
+	Quote   *QuoteResponse
+	Confirm ConfirmTransactionFunc
+	// BeforeSend gets the signature of every signed attempt before it is
+	// broadcast, so it can be saved. An error cancels the attempt.
+	BeforeSend func(signature string) error
+}
+
 type SwapResponse struct {
-	SwapTransaction string `json:"swapTransaction"`
+	SwapTransaction      string `json:"swapTransaction"`
+	LastValidBlockHeight uint64 `json:"lastValidBlockHeight"`
 }
 
 var (
-	tokenAddresses = map[string]string{
-		SolXEN: "6f8deE148nynnSiWshA9vLydEbJGpDeKh5G4PRgjmzG7",
-		// OGSolXEN: "EEqrab5tdnVdZv7a4AUAvGehDAtM8gWd7szwfyYbmGkM",
-		xencat: "7UN8WkBumTUCofVPXCPjNWQ6msQhzrg9tFQRP48Nmw5V",
-		PV:     "5px3a5LWR6CmiYX3ktpNnGYiEypfDdemRd74GDYbsJ2H",
-		ORE:    "oreoU2P8bN6jkk3jbaiVxYnG1dCXcYxwhwyK9jSybcp",
-	}
-	solXENPrice float64 = 0
-	// ogSolXENPrice float64
-	xencatPrice float64 = 0
-	pvPrice     float64 = 0
-	orePrice    float64 = 0
-	priceMutex  sync.RWMutex
+	swapMutex sync.Mutex
 )
 
 func InitJupiter() {
-	updatePrices()
-
-	// Update prices every 30 minutes
-	ticker := time.NewTicker(30 * time.Minute)
-	go func() {
-		for range ticker.C {
-			updatePrices()
-		}
-	}()
+	prices.start()
 }
 
-func updatePrices() {
-	solXENPrice = fetchPrice(SolXEN)
-	// ogSolXENPrice = fetchPrice(OGSolXEN)
-	xencatPrice = fetchPrice(xencat)
-	pvPrice = fetchPrice(PV)
-	orePrice = fetchPrice(ORE)
+// ExchangeSolForToken swaps SOL for the given token
+func ExchangeSolForToken(solAmount string, tokenName string, opts SwapOptions) (*SwapResult, error) {
+	return Swap(SOL, tokenName, solAmount, SwapModeExactIn, opts)
 }
 
-func fetchPrice(tokenName string) float64 {
-	LogToFile(fmt.Sprintf("Fetching price for %s", tokenName))
+// Swap swaps between any two supported tokens, SOL included, through the
+// configured swap backend.
+// In ExactIn mode amount is what we pay, in ExactOut mode what we receive.
+// Quotes above the price impact limit are refused. The swap is simulated
+// first and opts.Confirm decides whether it gets signed.
+// If the blockhash expires before confirmation the swap is re-quoted and
+// re-signed, but only after making sure no earlier attempt landed.
+// The result is read back from the confirmed transaction.
+func Swap(inputToken string, outputToken string, amount string, swapMode string, opts SwapOptions) (*SwapResult, error) {
+	// Step 1: Get the token mint addresses
+	inputMint, outputMint, err := resolveSwapMints(inputToken, outputToken)
+	if err != nil {
+		return nil, err
+	}
 
-	tokenAddress, ok := tokenAddresses[tokenName]
-	if !ok {
-		LogToFile(fmt.Sprintf("Unknown token: %s", tokenName))
-		return 0
+	owner, err := solana.PublicKeyFromBase58(GetGlobalPublicKey())
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet public key: %v", err)
+	}
+
+	// Only one swap at a time, so concurrent harvests can't spend the same SOL
+	swapMutex.Lock()
+	defer swapMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), swapTimeout)
+	defer cancel()
+
+	client := rpc.New(SolanaRPCURL)
+	swapper := getSwapper()
+	confirm := opts.Confirm
+	var sentSignatures []solana.Signature
+	sentQuotes := make(map[solana.Signature]*QuoteResponse)
+
+	for attempt := 1; attempt <= maxSwapAttempts; attempt++ {
+		// Step 2: Get a quote, or use the one the user already saw
+		quoteResp := opts.Quote
+		if attempt > 1 || quoteResp == nil {
+			quoteResp, err = getQuote(swapper, inputMint, outputMint, amount, swapMode, opts.SlippageBps)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get quote: %v", err)
+			}
+		}
+		if err := CheckPriceImpact(quoteResp, opts.MaxPriceImpactPct); err != nil {
+			return nil, err
+		}
+
+		// Step 3: Build the swap transaction
+		swapResp, err := swapper.SwapTransaction(quoteResp, owner.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute swap: %v", err)
+		}
+
+		// Offline backends are only verified, never signed or sent
+		if swapper.Offline() {
+			return offlineSwapResult(client, swapResp, owner, quoteResp)
+		}
+
+		// Step 4: Sign and send the transaction
+		sig, err := signAndSendTransaction(ctx, client, swapResp, getPrivateKey(), quoteResp, confirm, opts.BeforeSend)
+		if errors.Is(err, ErrBlockhashExpired) {
+			sentSignatures = append(sentSignatures, sig)
+			sentQuotes[sig] = quoteResp
+
+			// The user already confirmed this swap, re-quotes are only verified
+			confirm = nil
+
+			landedSig, landed, err := findLandedSignature(ctx, client, sentSignatures)
+			if err != nil {
+				return resolveUnknownSwap(client, owner, sentSignatures, sentQuotes, err)
+			}
+			if landed {
+				LogToFile(fmt.Sprintf("Earlier swap attempt %s landed", landedSig))
+				return getSwapResult(ctx, client, landedSig, owner, sentQuotes[landedSig]), nil
+			}
+
+			LogToFile(fmt.Sprintf("Swap attempt %d expired, re-quoting", attempt))
+			continue
+		}
+		if errors.Is(err, ErrTransactionStatusUnknown) {
+			sentSignatures = append(sentSignatures, sig)
+			sentQuotes[sig] = quoteResp
+			return resolveUnknownSwap(client, owner, sentSignatures, sentQuotes, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign and send transaction: %v", err)
+		}
+
+		// Step 5: Read the actual amounts from the confirmed transaction
+		return getSwapResult(ctx, client, sig, owner, quoteResp), nil
 	}
 
-	apiURL := fmt.Sprintf("%s?ids=%s&vsToken=%s", JupiterPriceURL, SOLMint, tokenAddress)
+	return nil, fmt.Errorf("swap not confirmed after %d attempts", maxSwapAttempts)
+}
 
-	resp, err := http.Get(apiURL)
+// ResolveSwap looks up earlier attempts of a swap by their signatures, like
+// after a restart. It returns the result of the attempt that landed, or nil
+// if none did. A landed swap whose transaction can't be read is an error,
+// its amounts would only be guesses.
+func ResolveSwap(inputToken string, outputToken string, signatures []string) (*SwapResult, error) {
+	inputMint, outputMint, err := resolveSwapMints(inputToken, outputToken)
 	if err != nil {
-		LogToFile(fmt.Sprintf("Failed to get price from Jupiter API: %v", err))
-		return 0
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	owner, err := solana.PublicKeyFromBase58(GetGlobalPublicKey())
 	if err != nil {
-		LogToFile(fmt.Sprintf("Failed to read response body: %v", err))
-		return 0
+		return nil, fmt.Errorf("invalid wallet public key: %v", err)
 	}
 
-	var priceResponse struct {
-		Data map[string]struct {
-			ID    string `json:"id"`
-			Type  string `json:"type"`
-			Price string `json:"price"`
-		} `json:"data"`
-		TimeTaken float64 `json:"timeTaken"`
+	sigs := make([]solana.Signature, 0, len(signatures))
+	for _, signature := range signatures {
+		sig, err := solana.SignatureFromBase58(signature)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature %s: %v", signature, err)
+		}
+		sigs = append(sigs, sig)
 	}
-	if err := json.Unmarshal(body, &priceResponse); err != nil {
-		LogToFile(fmt.Sprintf("Failed to parse JSON response: %v", err))
-		return 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), finalStatusTimeout)
+	defer cancel()
+
+	client := rpc.New(SolanaRPCURL)
+	landedSig, landed, err := findLandedSignature(ctx, client, sigs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check swap attempts: %v", err)
+	}
+	if !landed {
+		return nil, nil
 	}
 
-	priceData, ok := priceResponse.Data[SOLMint]
-	if !ok {
-		LogToFile("Price not found in response")
-		return 0
+	result := getSwapResult(ctx, client, landedSig, owner, &QuoteResponse{InputMint: inputMint, OutputMint: outputMint})
+	if result.FromQuote {
+		return nil, fmt.Errorf("swap %s landed but its transaction can't be read", landedSig)
 	}
+	return result, nil
+}
+
+// resolveUnknownSwap looks for a landed attempt once more when the status
+// of a sent swap couldn't be found, with its own deadline as the one of the
+// swap may have passed. Without a landed attempt the swap may still execute
+// and ErrSwapOutcomeUnknown is returned.
+func resolveUnknownSwap(client *rpc.Client, owner solana.PublicKey, signatures []solana.Signature, quotes map[solana.Signature]*QuoteResponse, cause error) (*SwapResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), finalStatusTimeout)
+	defer cancel()
 
-	price, err := strconv.ParseFloat(priceData.Price, 64)
+	landedSig, landed, err := findLandedSignature(ctx, client, signatures)
+	if err == nil && landed {
+		LogToFile(fmt.Sprintf("Swap attempt %s landed", landedSig))
+		return getSwapResult(ctx, client, landedSig, owner, quotes[landedSig]), nil
+	}
 	if err != nil {
-		LogToFile(fmt.Sprintf("Failed to parse price string: %v", err))
-		return 0
+		cause = fmt.Errorf("%v, final status check failed: %v", cause, err)
 	}
 
-	LogToFile(fmt.Sprintf("Fetched price for %s: %f", tokenName, price))
-	return price
+	LogToFile(fmt.Sprintf("Swap outcome unknown, signatures %v: %v", signatures, cause))
+	return nil, fmt.Errorf("%w (signatures %v): %v", ErrSwapOutcomeUnknown, signatures, cause)
 }
 
-// GetTokenExchangeAmount queries the amount of specified token that can be exchanged for a given amount of SOL
-func GetTokenExchangeAmount(solAmount string, tokenName string) (string, error) {
-	priceMutex.RLock()
-	defer priceMutex.RUnlock()
-
-	solAmountFloat, err := strconv.ParseFloat(solAmount, 64)
+// Helper function to adjust the amount based on token decimals
+func adjustAmountForDecimals(amount string, decimals int) (string, error) {
+	// Convert string to decimal
+	decimalAmount, err := decimal.NewFromString(amount)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse SOL amount: %w", err)
-	}
-
-	var price float64
-	switch tokenName {
-	case SolXEN:
-		price = solXENPrice
-	// case OGSolXEN:
-	// 	price = ogSolXENPrice
-	case xencat:
-		price = xencatPrice
-	case PV:
-		price = pvPrice
-	case ORE:
-		price = orePrice
-	default:
-		return "", fmt.Errorf("Unknown token: %s", tokenName)
+		return "", fmt.Errorf("failed to convert amount to decimal: %w", err)
 	}
 
-	if price == 0 {
-		return "", errors.New("Price not available")
+	// Multiply by 10^decimals and drop what the token can't represent
+	adjustedAmount := decimalAmount.Shift(int32(decimals)).Truncate(0)
+	if !adjustedAmount.IsPositive() {
+		return "", fmt.Errorf("amount %s is too small", amount)
 	}
 
-	result := solAmountFloat * price
+	LogToFile(fmt.Sprintf("Original amount: %s, Adjusted amount: %s", amount, adjustedAmount.String()))
 
-	formattedResult := fmt.Sprintf("%.6f", result)
-	LogToFile(fmt.Sprintf("Calculated result for %s: %s", tokenName, formattedResult))
+	// Return the string representation of the adjusted decimal
+	return adjustedAmount.String(), nil
+}
 
-	return formattedResult, nil
+// GetSwapQuote fetches a fresh Jupiter quote for swapping between two supported tokens
+func GetSwapQuote(inputToken string, outputToken string, amount string, swapMode string, slippageBps int) (*QuoteResponse, error) {
+	inputMint, outputMint, err := resolveSwapMints(inputToken, outputToken)
+	if err != nil {
+		return nil, err
+	}
+	return getQuote(getSwapper(), inputMint, outputMint, amount, swapMode, slippageBps)
 }
 
-func GetSolExchangeAmount(tokenAmount string, tokenName string) (string, error) {
-	priceMutex.RLock()
-	defer priceMutex.RUnlock()
+// resolveSwapMints returns the mints of a swap pair. SOL is swapped as wrapped SOL.
+func resolveSwapMints(inputToken string, outputToken string) (string, string, error) {
+	mintOf := func(tokenName string) (string, error) {
+		if tokenName == SOL {
+			return SOLMint, nil
+		}
+		token, ok := lookupToken(tokenName)
+		if !ok {
+			return "", fmt.Errorf("unknown token: %s", tokenName)
+		}
+		return token.Mint, nil
+	}
 
-	tokenAmountFloat, err := strconv.ParseFloat(tokenAmount, 64)
+	inputMint, err := mintOf(inputToken)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse token amount: %w", err)
-	}
-
-	var price float64
-	switch tokenName {
-	case SolXEN:
-		price = solXENPrice
-	case xencat:
-		price = xencatPrice
-	case PV:
-		price = pvPrice
-	case ORE:
-		price = orePrice
-	default:
-		return "", fmt.Errorf("Unknown token: %s", tokenName)
+		return "", "", err
+	}
+	outputMint, err := mintOf(outputToken)
+	if err != nil {
+		return "", "", err
 	}
+	if inputMint == outputMint {
+		return "", "", fmt.Errorf("cannot swap %s for itself", inputToken)
+	}
+	return inputMint, outputMint, nil
+}
 
-	if price == 0 {
-		return "", errors.New("Price not available")
+// maxInAmount is the most the quote may spend: the input amount for
+// ExactIn and the input amount plus slippage for ExactOut
+func (q *QuoteResponse) maxInAmount() (int64, error) {
+	amount := q.InAmount
+	if q.SwapMode == SwapModeExactOut {
+		amount = q.OtherAmountThreshold
 	}
+	return strconv.ParseInt(amount, 10, 64)
+}
 
-	result := tokenAmountFloat / price
+// minOutAmount is the least the quote may return: the output amount minus
+// slippage for ExactIn and the exact output amount for ExactOut
+func (q *QuoteResponse) minOutAmount() (int64, error) {
+	amount := q.OtherAmountThreshold
+	if q.SwapMode == SwapModeExactOut {
+		amount = q.OutAmount
+	}
+	return strconv.ParseInt(amount, 10, 64)
+}
 
-	formattedResult := fmt.Sprintf("%.9f", result) // 9 decimal places for SOL
-	LogToFile(fmt.Sprintf("Calculated SOL amount for %s %s: %s", tokenAmount, tokenName, formattedResult))
+// PriceImpactPercent returns the price impact of the quote in percent.
+// Jupiter reports it as a fraction.
+func (q *QuoteResponse) PriceImpactPercent() float64 {
+	impact, err := strconv.ParseFloat(q.PriceImpactPct, 64)
+	if err != nil {
+		return 0
+	}
+	return impact * 100
+}
 
-	return formattedResult, nil
+// CheckPriceImpact refuses quotes whose price impact exceeds maxPct. With a
+// limit set, a quote without a readable price impact is refused too.
+func CheckPriceImpact(quote *QuoteResponse, maxPct float64) error {
+	if maxPct <= 0 {
+		return nil
+	}
+	impact, err := strconv.ParseFloat(quote.PriceImpactPct, 64)
+	if err != nil || math.IsNaN(impact) {
+		return fmt.Errorf("quote has no valid price impact: %q", quote.PriceImpactPct)
+	}
+	if impact*100 > maxPct {
+		return fmt.Errorf("price impact %.2f%% exceeds the limit of %.2f%%", impact*100, maxPct)
+	}
+	return nil
 }
 
-func ExchangeSolForToken(solAmount string, tokenName string) (string, error) {
-	// Step 1: Get the token mint address
-	tokenMint, ok := tokenAddresses[tokenName]
-	if !ok {
-		return "", fmt.Errorf("unknown token: %s", tokenName)
+// FormatQuote formats the route, price impact, minimum output and fees of a
+// quote for display before a swap is confirmed
+func FormatQuote(quote *QuoteResponse) string {
+	var sb strings.Builder
+
+	inAmount, _ := strconv.ParseFloat(quote.InAmount, 64)
+	outAmount, _ := strconv.ParseFloat(quote.OutAmount, 64)
+	thresholdAmount, _ := strconv.ParseFloat(quote.OtherAmountThreshold, 64)
+	inDecimals := math.Pow10(mintDecimals(quote.InputMint))
+	outDecimals := math.Pow10(mintDecimals(quote.OutputMint))
+
+	sb.WriteString("QUOTE PREVIEW\n\n")
+	sb.WriteString(fmt.Sprintf("Pay: %s\n", formatSwapAmount(inAmount/inDecimals, quote.InputMint)))
+	sb.WriteString(fmt.Sprintf("Receive: %s\n", formatSwapAmount(outAmount/outDecimals, quote.OutputMint)))
+	if quote.SwapMode == SwapModeExactOut {
+		sb.WriteString(fmt.Sprintf("Maximum paid: %s\n", formatSwapAmount(thresholdAmount/inDecimals, quote.InputMint)))
+	} else {
+		sb.WriteString(fmt.Sprintf("Minimum received: %s\n", formatSwapAmount(thresholdAmount/outDecimals, quote.OutputMint)))
+	}
+	sb.WriteString(fmt.Sprintf("Slippage: %.2f%%\n", float64(quote.SlippageBps)/100))
+	sb.WriteString(fmt.Sprintf("Price impact: %.4f%%\n", quote.PriceImpactPercent()))
+
+	var route []string
+	fees := make(map[string]float64)
+	for _, step := range quote.RoutePlan {
+		route = append(route, fmt.Sprintf("%s (%d%%)", step.SwapInfo.Label, step.Percent))
+		fee, err := strconv.ParseFloat(step.SwapInfo.FeeAmount, 64)
+		if err == nil {
+			fees[step.SwapInfo.FeeMint] += fee
+		}
 	}
+	sb.WriteString("Route: " + strings.Join(route, " -> ") + "\n")
 
-	// Step 2: Get a quote
-	quoteResp, err := getQuote(SOLMint, tokenMint, solAmount)
-	if err != nil {
-		return "", fmt.Errorf("failed to get quote: %v", err)
+	var feeParts []string
+	for mint, fee := range fees {
+		feeParts = append(feeParts, formatSwapAmount(fee/math.Pow10(mintDecimals(mint)), mint))
 	}
+	sort.Strings(feeParts)
+	sb.WriteString("Pool fees: " + strings.Join(feeParts, ", "))
 
-	// Step 3: Execute the swap
-	swapResp, err := executeSwap(quoteResp, GetGlobalPublicKey())
-	if err != nil {
-		return "", fmt.Errorf("failed to execute swap: %v", err)
+	return sb.String()
+}
+
+func getQuote(swapper Swapper, inputMint string, outputMint string, amount string, swapMode string, slippageBps int) (*QuoteResponse, error) {
+	if slippageBps <= 0 {
+		slippageBps = DefaultSlippageBps
 	}
 
-	// Step 4: Parse the output amount
-	outAmount, err := parseAmount(quoteResp.OutAmount)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse out amount: %v", err)
+	// The amount is in the input token for ExactIn and in the output token for ExactOut
+	amountMint := inputMint
+	switch swapMode {
+	case SwapModeExactIn:
+	case SwapModeExactOut:
+		amountMint = outputMint
+	default:
+		return nil, fmt.Errorf("unknown swap mode: %s", swapMode)
 	}
 
-	// Step 5: Sign and send the transaction
-	err = signAndSendTransaction(swapResp.SwapTransaction, getPrivateKey())
+	adjustedAmount, err := adjustAmountForDecimals(amount, mintDecimals(amountMint))
 	if err != nil {
-		return "", fmt.Errorf("failed to sign and send transaction: %v", err)
+		return nil, fmt.Errorf("failed to adjust amount: %w", err)
 	}
 
-	// Log the transaction for the user to sign and send
-	LogToFile(fmt.Sprintf("Swap transaction: %s", swapResp.SwapTransaction))
-
-	return strconv.FormatFloat(outAmount/1_000_000, 'f', -1, 64), nil
-}
+	quoteResp, err := swapper.Quote(inputMint, outputMint, adjustedAmount, swapMode, slippageBps)
+	if err != nil {
+		return nil, err
+	}
 
-// Helper function to adjust the amount based on token decimals
-func adjustAmountForDecimals(amount string) (string, error) {
-	// Convert string to decimal
-	decimalAmount, err := decimal.NewFromString(amount)
+	// Format the JSON for logging
+	formattedJSON, err := json.MarshalIndent(quoteResp, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to convert amount to decimal: %w", err)
+		LogToFile(fmt.Sprintf("Error formatting JSON: %v", err))
+	} else {
+		LogToFile(fmt.Sprintf("Quote Response:\n%s", string(formattedJSON)))
 	}
 
-	// Multiply by 10^9 (1_000_000_000)
-	adjustedAmount := decimalAmount.Mul(decimal.NewFromInt(1_000_000_000))
+	return quoteResp, nil
+}
 
-	LogToFile(fmt.Sprintf("Original amount: %s, Adjusted amount: %s", amount, adjustedAmount.String()))
+// JupiterSwapper quotes and builds swaps through the Jupiter Swap API
+type JupiterSwapper struct {
+	BaseURL string
+	APIKey  string
+	client  *http.Client
+}
 
-	// Return the string representation of the adjusted decimal
-	return adjustedAmount.String(), nil
+// NewJupiterSwapper creates a Jupiter backend. An empty base URL uses the
+// public endpoint, or api.jup.ag with an API key, which is sent with every
+// request if set.
+func NewJupiterSwapper(baseURL string, apiKey string) *JupiterSwapper {
+	if baseURL == "" {
+		baseURL = DefaultJupiterBaseURL
+		if apiKey != "" {
+			baseURL = KeyedJupiterBaseURL
+		}
+	}
+	return &JupiterSwapper{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
 }
 
-func getQuote(inputMint string, outputMint string, inAmount string) (*QuoteResponse, error) {
+func (j *JupiterSwapper) Name() string {
+	return "Jupiter"
+}
 
-	// Adjust inAmount
-	adjustedAmount, err := adjustAmountForDecimals(inAmount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to adjust input amount: %w", err)
-	}
+func (j *JupiterSwapper) Offline() bool {
+	return false
+}
 
-	url := fmt.Sprintf("%s?inputMint=%s&outputMint=%s&amount=%s&slippageBps=50",
-		JupiterQuoteURL, inputMint, outputMint, adjustedAmount)
+func (j *JupiterSwapper) Quote(inputMint string, outputMint string, amount string, swapMode string, slippageBps int) (*QuoteResponse, error) {
+	url := fmt.Sprintf("%s/quote?inputMint=%s&outputMint=%s&amount=%s&slippageBps=%d&swapMode=%s",
+		j.BaseURL, inputMint, outputMint, amount, slippageBps, swapMode)
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := j.do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	var quoteResp QuoteResponse
-	err = json.Unmarshal(body, &quoteResp)
-	if err != nil {
-		return nil, err
+	if err := json.Unmarshal(body, &quoteResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal quote: %w", err)
 	}
-
-	// Format the JSON for logging
-	formattedJSON, err := json.MarshalIndent(quoteResp, "", "  ")
-	if err != nil {
-		LogToFile(fmt.Sprintf("Error formatting JSON: %v\nRaw response:\n%s", err, string(body)))
-	} else {
-		LogToFile(fmt.Sprintf("Quote Response:\n%s", string(formattedJSON)))
+	if quoteResp.InAmount == "" || quoteResp.OutAmount == "" {
+		LogToFile(fmt.Sprintf("Raw response body:\n%s", string(body)))
+		return nil, fmt.Errorf("empty quote returned")
 	}
 
 	return &quoteResp, nil
 }
 
-func executeSwap(quote *QuoteResponse, userPublicKey string) (*SwapResponse, error) {
+func (j *JupiterSwapper) SwapTransaction(quote *QuoteResponse, userPublicKey string) (*SwapResponse, error) {
 	swapRequest := SwapRequest{
 		QuoteResponse:             *quote,
 		UserPublicKey:             userPublicKey,
@@ -338,32 +517,21 @@ func executeSwap(quote *QuoteResponse, userPublicKey string) (*SwapResponse, err
 		return nil, err
 	}
 
-	resp, err := http.Post(JupiterSwapURL, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(http.MethodPost, j.BaseURL+"/swap", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := j.do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	var swapResp SwapResponse
-	err = json.Unmarshal(body, &swapResp)
-	if err != nil {
+	if err := json.Unmarshal(body, &swapResp); err != nil {
 		// Log the raw response body
 		LogToFile(fmt.Sprintf("Raw response body:\n%s", string(body)))
-
-		// Check if the response contains an error message
-		var errorResp struct {
-			Error string `json:"error"`
-		}
-		if jsonErr := json.Unmarshal(body, &errorResp); jsonErr == nil && errorResp.Error != "" {
-			return nil, fmt.Errorf("API error: %s", errorResp.Error)
-		}
-
-		// If it's not a known error format, return the original error
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
@@ -372,69 +540,107 @@ func executeSwap(quote *QuoteResponse, userPublicKey string) (*SwapResponse, err
 	return &swapResp, nil
 }
 
-func signAndSendTransaction(transaction string, privateKey string) error {
-	// 1. Decode the transaction data
-	decodedTransaction, err := base64.StdEncoding.DecodeString(transaction)
+// do sends the request with the API key and turns error responses into errors
+func (j *JupiterSwapper) do(req *http.Request) ([]byte, error) {
+	if j.APIKey != "" {
+		req.Header.Set("x-api-key", j.APIKey)
+	}
+
+	resp, err := j.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to decode transaction: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if the response contains an error message
+	var errorResp struct {
+		Error string `json:"error"`
+	}
+	if jsonErr := json.Unmarshal(body, &errorResp); jsonErr == nil && errorResp.Error != "" {
+		return nil, fmt.Errorf("API error: %s", errorResp.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Jupiter API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// decodeSwapTransaction parses the base64 transaction of a swap response
+func decodeSwapTransaction(swapResp *SwapResponse) (*solana.Transaction, error) {
+	// Decode the transaction data
+	decodedTransaction, err := base64.StdEncoding.DecodeString(swapResp.SwapTransaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %v", err)
 	}
 
 	LogToFile(fmt.Sprintf("Decoded transaction length: %d bytes", len(decodedTransaction)))
 
 	if len(decodedTransaction) == 0 {
-		return fmt.Errorf("decoded transaction is empty")
+		return nil, fmt.Errorf("decoded transaction is empty")
 	}
 
-	// 2. Parse the transaction
+	// Parse the transaction
 	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(decodedTransaction))
 	if err != nil {
-		return fmt.Errorf("failed to parse transaction (length: %d bytes): %w", len(decodedTransaction), err)
+		return nil, fmt.Errorf("failed to parse transaction (length: %d bytes): %w", len(decodedTransaction), err)
 	}
+	return tx, nil
+}
 
-	// 3. Sign the transaction using the private key
-	kp, err := solana.PrivateKeyFromBase58(privateKey)
+func signAndSendTransaction(ctx context.Context, client *rpc.Client, swapResp *SwapResponse, privateKey string, quote *QuoteResponse, confirm ConfirmTransactionFunc, beforeSend func(string) error) (solana.Signature, error) {
+	// 1-2. Decode and parse the transaction
+	tx, err := decodeSwapTransaction(swapResp)
 	if err != nil {
-		return fmt.Errorf("failed to parse private key: %v", err)
+		return solana.Signature{}, err
 	}
-	tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(kp.PublicKey()) {
-			return &kp
-		}
-		return nil
-	})
 
-	// 4. Serialize the signed transaction
-	signedTx, err := tx.MarshalBinary()
+	kp, err := solana.PrivateKeyFromBase58(privateKey)
 	if err != nil {
-		return fmt.Errorf("failed to serialize signed transaction: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to parse private key: %v", err)
 	}
 
-	// 5. Send the transaction to the Solana network
-	client := rpc.New("https://api.mainnet-beta.solana.com")
-	// Convert signedTx to *solana.Transaction
-	tx, err = solana.TransactionFromDecoder(bin.NewBinDecoder(signedTx))
-	if err != nil {
-		return fmt.Errorf("failed to decode transaction: %v", err)
+	// 3. Check the transaction against our signing policy
+	if err := verifySwapTransaction(client, tx, kp.PublicKey(), quote); err != nil {
+		LogToFile(fmt.Sprintf("Swap transaction rejected: %v", err))
+		return solana.Signature{}, fmt.Errorf("swap transaction rejected: %v", err)
 	}
-	sig, err := client.SendTransaction(context.Background(), tx)
+
+	// 4. Simulate the transaction, check its token flows and let the user confirm it
+	preview, err := SimulateTransaction(client, tx, kp.PublicKey())
 	if err != nil {
-		return fmt.Errorf("failed to send transaction: %v", err)
+		return solana.Signature{}, err
 	}
-	LogToFile(fmt.Sprintf("Transaction sent: %s", sig))
-	return nil
-}
-
-func parseAmount(amountStr string) (float64, error) {
-	// Check if the string is empty
-	if amountStr == "" {
-		return 0, fmt.Errorf("amount string is empty")
+	if err := verifySwapFlows(preview, quote); err != nil {
+		LogToFile(fmt.Sprintf("Swap transaction rejected: %v", err))
+		return solana.Signature{}, fmt.Errorf("swap transaction rejected: %v", err)
+	}
+	if err := approvePreview(preview, confirm); err != nil {
+		return solana.Signature{}, err
 	}
 
-	// Try to parse the string to float64
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	// 5. Sign the transaction using the private key
+	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(kp.PublicKey()) {
+			return &kp
+		}
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse amount: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
 	}
 
-	return amount, nil
+	// 6. Let the caller record the signature, then send the transaction and
+	// rebroadcast until confirmed or expired
+	if beforeSend != nil {
+		if err := beforeSend(tx.Signatures[0].String()); err != nil {
+			return solana.Signature{}, fmt.Errorf("transaction not sent: %v", err)
+		}
+	}
+	return sendAndConfirmTransaction(ctx, client, tx, swapResp.LastValidBlockHeight)
 }
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPriceImpact(t *testing.T) {
+	tests := []struct {
+		name string
+		// Jupiter reports the impact as a fraction
+		priceImpactPct string
+		maxPct         float64
+		wantErr        string
+	}{
+		{"no impact", "0", 1, ""},
+		{"below the limit", "0.0099", 1, ""},
+		{"at the limit", "0.01", 1, ""},
+		{"above the limit", "0.0101", 1, "price impact 1.01% exceeds the limit of 1.00%"},
+		{"price improvement", "-0.002", 1, ""},
+		{"no limit", "0.5", 0, ""},
+		{"no limit, unreadable impact", "", 0, ""},
+		{"empty impact", "", 1, "no valid price impact"},
+		{"unreadable impact", "n/a", 1, "no valid price impact"},
+		{"NaN impact", "NaN", 1, "no valid price impact"},
+		{"infinite impact", "+Inf", 1, "exceeds the limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPriceImpact(&QuoteResponse{PriceImpactPct: tt.priceImpactPct}, tt.maxPct)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewJupiterSwapperBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		apiKey  string
+		want    string
+	}{
+		{name: "public endpoint", want: DefaultJupiterBaseURL},
+		{name: "keyed endpoint", apiKey: "key", want: KeyedJupiterBaseURL},
+		{name: "configured endpoint", baseURL: "https://jupiter.example/swap/v1/", apiKey: "key", want: "https://jupiter.example/swap/v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewJupiterSwapper(tt.baseURL, tt.apiKey).BaseURL; got != tt.want {
+				t.Errorf("BaseURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
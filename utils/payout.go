@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const miningConfigFileName = "miningConfig.json"
+
+// DefaultReferralCode is the unMineable referral code appended to the miner user
+const DefaultReferralCode = "plxp-imd8"
+
+// unMineable referral codes are two groups of 4 lowercase letters or digits
+var referralCodePattern = regexp.MustCompile(`^[a-z0-9]{4}-[a-z0-9]{4}$`)
+
+// MiningConfig holds the unMineable payout and referral settings shared by all miners
+type MiningConfig struct {
+	PayoutCoin string `json:"payoutCoin"`
+	// Empty pays a SOL payout to the wallet
+	PayoutAddress string `json:"payoutAddress"`
+	// Empty mines without a referral
+	ReferralCode string `json:"referralCode"`
+	// unMineable API, empty for the public one
+	UnmineableBaseURL string `json:"unmineableBaseURL,omitempty"`
+}
+
+// Payout address formats per chain
+var (
+	evmAddressPattern  = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	btcAddressPattern  = regexp.MustCompile(`^(bc1[02-9ac-hj-np-z]{11,71}|[13][1-9A-HJ-NP-Za-km-z]{25,34})$`)
+	ltcAddressPattern  = regexp.MustCompile(`^(ltc1[02-9ac-hj-np-z]{11,71}|[LM3][1-9A-HJ-NP-Za-km-z]{26,33})$`)
+	dogeAddressPattern = regexp.MustCompile(`^[DA9][1-9A-HJ-NP-Za-km-z]{33}$`)
+	xmrAddressPattern  = regexp.MustCompile(`^[48][1-9A-HJ-NP-Za-km-z]{94}([1-9A-HJ-NP-Za-km-z]{11})?$`)
+	trxAddressPattern  = regexp.MustCompile(`^T[1-9A-HJ-NP-Za-km-z]{33}$`)
+	adaAddressPattern  = regexp.MustCompile(`^addr1[02-9ac-hj-np-z]{53,}$`)
+)
+
+// PayoutCoins are the unMineable payout coins with a known address format
+var PayoutCoins = []string{SOL, "BTC", "ETH", "LTC", "DOGE", "XMR", "TRX", "ADA", "SHIB", "BNB", "MATIC"}
+
+var payoutAddressValidators = map[string]func(string) bool{
+	SOL: func(address string) bool {
+		_, err := solana.PublicKeyFromBase58(address)
+		return err == nil
+	},
+	"BTC":   btcAddressPattern.MatchString,
+	"ETH":   evmAddressPattern.MatchString,
+	"LTC":   ltcAddressPattern.MatchString,
+	"DOGE":  dogeAddressPattern.MatchString,
+	"XMR":   xmrAddressPattern.MatchString,
+	"TRX":   trxAddressPattern.MatchString,
+	"ADA":   adaAddressPattern.MatchString,
+	"SHIB":  evmAddressPattern.MatchString,
+	"BNB":   evmAddressPattern.MatchString,
+	"MATIC": evmAddressPattern.MatchString,
+}
+
+// ValidatePayoutAddress checks that the address is valid on the chain of the coin
+func ValidatePayoutAddress(coin string, address string) error {
+	validate, ok := payoutAddressValidators[coin]
+	if !ok {
+		return fmt.Errorf("unsupported payout coin: %s", coin)
+	}
+	if address == "" {
+		return fmt.Errorf("a %s payout needs a payout address", coin)
+	}
+	if !validate(address) {
+		return fmt.Errorf("invalid %s address: %s", coin, address)
+	}
+	return nil
+}
+
+// ValidateReferralCode checks the unMineable referral code format, empty is allowed
+func ValidateReferralCode(code string) error {
+	if code != "" && !referralCodePattern.MatchString(code) {
+		return fmt.Errorf("invalid referral code %q, expected a code like %s", code, DefaultReferralCode)
+	}
+	return nil
+}
+
+func ReadMiningConfigFile() (MiningConfig, error) {
+	configPath := filepath.Join(GetExecutablePath(), miningConfigFileName)
+	file, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultMiningConfig(), nil
+		}
+		return MiningConfig{}, err
+	}
+
+	// Settings missing from older config files keep their default
+	config := defaultMiningConfig()
+	err = json.Unmarshal(file, &config)
+	if err != nil {
+		return MiningConfig{}, err
+	}
+
+	if config.PayoutCoin == "" {
+		config.PayoutCoin = SOL
+	}
+
+	return config, nil
+}
+
+func defaultMiningConfig() MiningConfig {
+	return MiningConfig{
+		PayoutCoin:   SOL,
+		ReferralCode: DefaultReferralCode,
+	}
+}
+
+// WriteMiningConfigFile validates the payout and referral code and saves them
+func WriteMiningConfigFile(config MiningConfig) error {
+	config.PayoutCoin = strings.ToUpper(strings.TrimSpace(config.PayoutCoin))
+	config.PayoutAddress = strings.TrimSpace(config.PayoutAddress)
+	config.ReferralCode = strings.ToLower(strings.TrimSpace(config.ReferralCode))
+
+	if err := ValidateReferralCode(config.ReferralCode); err != nil {
+		return err
+	}
+
+	// A SOL payout without an address goes to the wallet
+	if config.PayoutCoin != SOL || config.PayoutAddress != "" {
+		if err := ValidatePayoutAddress(config.PayoutCoin, config.PayoutAddress); err != nil {
+			return err
+		}
+	}
+
+	configPath := filepath.Join(GetExecutablePath(), miningConfigFileName)
+	file, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(configPath, file, 0644)
+}
+
+// GetPayout returns the payout coin and address, the wallet for a default SOL payout
+func GetPayout() (coin string, address string, err error) {
+	config, err := ReadMiningConfigFile()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read mining config: %v", err)
+	}
+
+	address = config.PayoutAddress
+	if config.PayoutCoin == SOL && address == "" {
+		address = GetGlobalPublicKey()
+	}
+	if err := ValidatePayoutAddress(config.PayoutCoin, address); err != nil {
+		return "", "", err
+	}
+	return config.PayoutCoin, address, nil
+}
+
+// PayoutIsSOL reports whether mining pays out in SOL. Harvesting and the
+// solXEN conversions only make sense for a SOL payout.
+func PayoutIsSOL() bool {
+	config, err := ReadMiningConfigFile()
+	return err == nil && config.PayoutCoin == SOL
+}
+
+// GetReferralCode returns the configured referral code, empty if it was removed
+func GetReferralCode() string {
+	config, err := ReadMiningConfigFile()
+	if err != nil {
+		return DefaultReferralCode
+	}
+	return config.ReferralCode
+}
+
+// SetReferralCode validates the referral code and saves it, empty removes it
+func SetReferralCode(code string) error {
+	config, err := ReadMiningConfigFile()
+	if err != nil {
+		return fmt.Errorf("failed to read mining config: %v", err)
+	}
+	config.ReferralCode = code
+	return WriteMiningConfigFile(config)
+}
+
+// GetMiningUser builds the unMineable miner user COIN:address.worker#referral
+func GetMiningUser(workerName string) (string, error) {
+	coin, address, err := GetPayout()
+	if err != nil {
+		return "", err
+	}
+
+	user := fmt.Sprintf("%s:%s.%s", coin, address, workerName)
+	if referralCode := GetReferralCode(); referralCode != "" {
+		user += "#" + referralCode
+	}
+	return user, nil
+}
@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const payoutLedgerFileName = "payoutLedger.json"
+
+// A payout matches a transfer that is within this many SOL of its amount
+const payoutAmountTolerance = 0.000001
+
+const payoutReconcileTimeout = 5 * time.Minute
+
+// PayoutReconciliation is the outcome of matching a payout to its transaction
+type PayoutReconciliation string
+
+const (
+	// The wallet received the paid amount in the payout transaction
+	PayoutMatched PayoutReconciliation = "matched"
+	// The payout transaction credited the wallet a different amount
+	PayoutMismatched PayoutReconciliation = "mismatched"
+	// The payout transaction was not found on-chain or failed
+	PayoutMissing PayoutReconciliation = "missing"
+	// The payout transaction couldn't be looked up, the RPC node timed out or
+	// refused the request. It is checked again on the next run.
+	PayoutUnchecked PayoutReconciliation = "unchecked"
+	// unMineable has not sent the payout yet, it has no transaction
+	PayoutUnsent PayoutReconciliation = "unsent"
+)
+
+// PayoutRecord is a payout and what the wallet received for it
+type PayoutRecord struct {
+	UnmineablePayout
+	ReceivedSOL    float64              `json:"receivedSOL"`
+	Reconciliation PayoutReconciliation `json:"reconciliation"`
+	Note           string               `json:"note,omitempty"`
+	CheckedAt      time.Time            `json:"checkedAt"`
+}
+
+// Flagged reports whether the payout needs a look
+func (r PayoutRecord) Flagged() bool {
+	return r.Reconciliation == PayoutMismatched || r.Reconciliation == PayoutMissing
+}
+
+// PayoutLedger keeps every payout seen for the payout address, so the
+// running totals survive payouts dropping out of the unMineable history
+type PayoutLedger struct {
+	Address       string         `json:"address"`
+	Payouts       []PayoutRecord `json:"payouts"`
+	TotalPaid     float64        `json:"totalPaid"`
+	TotalReceived float64        `json:"totalReceived"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+}
+
+// LoadPayoutLedger returns the saved ledger, or nil if there is none
+func LoadPayoutLedger() (*PayoutLedger, error) {
+	file, err := os.ReadFile(filepath.Join(GetExecutablePath(), payoutLedgerFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ledger PayoutLedger
+	if err := json.Unmarshal(file, &ledger); err != nil {
+		return nil, err
+	}
+	return &ledger, nil
+}
+
+// ReconcilePayouts fetches the unMineable payouts and matches each one to
+// the SOL its transaction credited the wallet. The transactions are looked
+// up by hash, so old payouts are found however busy the wallet is.
+// Matched payouts are not checked again.
+func ReconcilePayouts() (*PayoutLedger, error) {
+	coin, address, err := GetPayout()
+	if err != nil {
+		return nil, err
+	}
+	if coin != SOL {
+		return nil, fmt.Errorf("payouts can only be reconciled on-chain for a SOL payout, mining pays out in %s", coin)
+	}
+
+	// 1. Merge the payout history into the saved ledger
+	payouts, err := GetUnmineablePayouts(address, coin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout history: %v", err)
+	}
+
+	ledger, err := LoadPayoutLedger()
+	if err != nil {
+		LogToFile(fmt.Sprintf("Failed to load payout ledger, starting a new one: %v", err))
+	}
+	if ledger == nil || ledger.Address != address {
+		ledger = &PayoutLedger{Address: address}
+	}
+	ledger.merge(payouts)
+
+	// 2. Match the payouts to their transactions
+	ctx, cancel := context.WithTimeout(context.Background(), payoutReconcileTimeout)
+	defer cancel()
+	client := rpc.New(SolanaRPCURL)
+
+	owner, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payout address: %v", err)
+	}
+
+	for i := range ledger.Payouts {
+		record := &ledger.Payouts[i]
+		if record.Reconciliation == PayoutMatched {
+			continue
+		}
+		record.reconcile(ctx, client, owner)
+	}
+
+	ledger.updateTotals()
+	if err := ledger.save(); err != nil {
+		LogToFile(fmt.Sprintf("Failed to save payout ledger: %v", err))
+	}
+
+	LogToFile(fmt.Sprintf("Reconciled payouts: %s", ledger))
+	return ledger, nil
+}
+
+// merge adds new payouts and refreshes the ones still waiting for a transaction
+func (l *PayoutLedger) merge(payouts []UnmineablePayout) {
+	index := make(map[string]int)
+	for i, record := range l.Payouts {
+		index[record.key()] = i
+	}
+
+	for _, payout := range payouts {
+		record := PayoutRecord{UnmineablePayout: payout}
+		if i, ok := index[record.key()]; ok {
+			// Keep the reconciliation, the payout itself may have changed status
+			l.Payouts[i].UnmineablePayout = payout
+			continue
+		}
+		// A payout that got its transaction replaces the unsent entry
+		unsent := PayoutRecord{UnmineablePayout: UnmineablePayout{Amount: payout.Amount, Date: payout.Date}}
+		if i, ok := index[unsent.key()]; ok {
+			l.Payouts[i] = record
+			index[record.key()] = i
+			continue
+		}
+		index[record.key()] = len(l.Payouts)
+		l.Payouts = append(l.Payouts, record)
+	}
+
+	// Newest first
+	sort.SliceStable(l.Payouts, func(i, j int) bool {
+		return l.Payouts[i].Date.After(l.Payouts[j].Date)
+	})
+}
+
+// key identifies a payout by its transaction, or by amount and date while unsent
+func (r PayoutRecord) key() string {
+	if r.TxHash != "" {
+		return r.TxHash
+	}
+	return fmt.Sprintf("%d/%s", r.Date.Unix(), strconv.FormatFloat(r.Amount, 'f', -1, 64))
+}
+
+// reconcile matches the payout to the SOL its transaction credited the owner
+func (r *PayoutRecord) reconcile(ctx context.Context, client *rpc.Client, owner solana.PublicKey) {
+	r.CheckedAt = time.Now()
+
+	if r.TxHash == "" {
+		r.setReceived(PayoutUnsent, 0, "")
+		return
+	}
+
+	signature, err := solana.SignatureFromBase58(r.TxHash)
+	if err != nil {
+		r.setReceived(PayoutMissing, 0, fmt.Sprintf("invalid transaction hash: %v", err))
+		return
+	}
+
+	received, err := getReceivedSOL(ctx, client, owner, signature)
+	r.applyLookup(received, err)
+}
+
+// applyLookup records the outcome of looking up the payout transaction.
+// Only a lookup that definitively found nothing flags the payout as
+// missing, any other error leaves it unchecked until the next run.
+func (r *PayoutRecord) applyLookup(received float64, err error) {
+	switch {
+	case errors.Is(err, rpc.ErrNotFound):
+		r.setReceived(PayoutMissing, 0, "transaction not found")
+		return
+	case errors.Is(err, errTransactionFailed):
+		r.setReceived(PayoutMissing, 0, "transaction failed")
+		return
+	case errors.Is(err, errWalletNotInTransaction):
+		r.setReceived(PayoutMismatched, 0, "transaction doesn't pay the wallet")
+		return
+	case err != nil:
+		// Keep what an earlier lookup found
+		r.Reconciliation = PayoutUnchecked
+		r.Note = fmt.Sprintf("failed to get transaction: %v", err)
+		return
+	}
+
+	if math.Abs(received-r.Amount) > payoutAmountTolerance {
+		r.setReceived(PayoutMismatched, received, fmt.Sprintf("received %s SOL for %s SOL paid",
+			strconv.FormatFloat(received, 'f', -1, 64), strconv.FormatFloat(r.Amount, 'f', -1, 64)))
+		return
+	}
+	r.setReceived(PayoutMatched, received, "")
+}
+
+func (r *PayoutRecord) setReceived(reconciliation PayoutReconciliation, received float64, note string) {
+	r.Reconciliation = reconciliation
+	r.ReceivedSOL = received
+	r.Note = note
+}
+
+var (
+	// errTransactionFailed is returned for a transaction that landed with an error
+	errTransactionFailed = errors.New("transaction failed")
+	// errWalletNotInTransaction is returned for a transaction that doesn't touch the wallet
+	errWalletNotInTransaction = errors.New("wallet is not an account of the transaction")
+)
+
+// getReceivedSOL returns how much SOL the transaction credited the owner
+func getReceivedSOL(ctx context.Context, client *rpc.Client, owner solana.PublicKey, sig solana.Signature) (float64, error) {
+	tx, err := getConfirmedTransaction(ctx, client, sig)
+	if err != nil {
+		return 0, err
+	}
+	if tx == nil || tx.Meta == nil || tx.Transaction == nil {
+		return 0, rpc.ErrNotFound
+	}
+	if tx.Meta.Err != nil {
+		return 0, errTransactionFailed
+	}
+	parsed, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode transaction: %v", err)
+	}
+
+	// Balances follow the static keys and then the lookup table addresses
+	keys := append(solana.PublicKeySlice{}, parsed.Message.AccountKeys...)
+	keys = append(keys, tx.Meta.LoadedAddresses.Writable...)
+	keys = append(keys, tx.Meta.LoadedAddresses.ReadOnly...)
+
+	for i, key := range keys {
+		if !key.Equals(owner) {
+			continue
+		}
+		if i >= len(tx.Meta.PreBalances) || i >= len(tx.Meta.PostBalances) {
+			break
+		}
+		lamports := int64(tx.Meta.PostBalances[i]) - int64(tx.Meta.PreBalances[i])
+		return float64(lamports) / 1e9, nil
+	}
+	return 0, errWalletNotInTransaction
+}
+
+func (l *PayoutLedger) updateTotals() {
+	l.TotalPaid = 0
+	l.TotalReceived = 0
+	for _, record := range l.Payouts {
+		if record.TxHash == "" {
+			continue
+		}
+		l.TotalPaid += record.Amount
+		l.TotalReceived += record.ReceivedSOL
+	}
+	l.UpdatedAt = time.Now()
+}
+
+// Flagged returns the missing and mismatched payouts
+func (l *PayoutLedger) Flagged() []PayoutRecord {
+	var flagged []PayoutRecord
+	for _, record := range l.Payouts {
+		if record.Flagged() {
+			flagged = append(flagged, record)
+		}
+	}
+	return flagged
+}
+
+// String summarizes the ledger for the log
+func (l *PayoutLedger) String() string {
+	counts := make(map[PayoutReconciliation]int)
+	for _, record := range l.Payouts {
+		counts[record.Reconciliation]++
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d payouts, %s SOL paid, %s SOL received",
+		len(l.Payouts),
+		strconv.FormatFloat(l.TotalPaid, 'f', 9, 64),
+		strconv.FormatFloat(l.TotalReceived, 'f', 9, 64)))
+	sb.WriteString(fmt.Sprintf(" (%d matched, %d mismatched, %d missing, %d unchecked, %d unsent)",
+		counts[PayoutMatched], counts[PayoutMismatched], counts[PayoutMissing], counts[PayoutUnchecked], counts[PayoutUnsent]))
+	return sb.String()
+}
+
+func (l *PayoutLedger) save() error {
+	file, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a crash can't leave a truncated ledger
+	path := filepath.Join(GetExecutablePath(), payoutLedgerFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, file, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
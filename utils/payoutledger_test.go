@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestPayoutApplyLookup(t *testing.T) {
+	tests := []struct {
+		name         string
+		received     float64
+		err          error
+		want         PayoutReconciliation
+		wantReceived float64
+	}{
+		{name: "paid amount received", received: 0.1, want: PayoutMatched, wantReceived: 0.1},
+		{name: "less received", received: 0.09, want: PayoutMismatched, wantReceived: 0.09},
+		{name: "transaction not found", err: rpc.ErrNotFound, want: PayoutMissing},
+		{name: "transaction failed", err: errTransactionFailed, want: PayoutMissing},
+		{name: "wallet not paid", err: errWalletNotInTransaction, want: PayoutMismatched},
+		// Transient errors keep what the last lookup found
+		{name: "rate limited", err: errors.New("(429) Too Many Requests"), want: PayoutUnchecked, wantReceived: 0.05},
+		{name: "timed out", err: fmt.Errorf("lookup: %w", context.DeadlineExceeded), want: PayoutUnchecked, wantReceived: 0.05},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := PayoutRecord{
+				UnmineablePayout: UnmineablePayout{Amount: 0.1},
+				ReceivedSOL:      0.05,
+				Reconciliation:   PayoutMismatched,
+			}
+			record.applyLookup(tt.received, tt.err)
+			if record.Reconciliation != tt.want || record.ReceivedSOL != tt.wantReceived {
+				t.Errorf("got %s with %v SOL, want %s with %v SOL", record.Reconciliation, record.ReceivedSOL, tt.want, tt.wantReceived)
+			}
+			if record.Flagged() != (tt.want == PayoutMismatched || tt.want == PayoutMissing) {
+				t.Errorf("Flagged() = %v for %s", record.Flagged(), record.Reconciliation)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortfolioHolding is the USD value of one wallet balance
+type PortfolioHolding struct {
+	Symbol     string
+	Amount     float64
+	ValueUSD   float64
+	SharePct   float64
+	Change24h  float64
+	HasChange  bool
+	Priced     bool
+	StalePrice bool
+}
+
+// Portfolio values the wallet and the pending unMineable balance in USD
+type Portfolio struct {
+	Holdings   []PortfolioHolding
+	Pending    *PortfolioHolding
+	WalletUSD  float64
+	PendingUSD float64
+	TotalUSD   float64
+	UpdatedAt  time.Time
+}
+
+// GetPortfolio values the SOL and token balances of the wallet and the
+// pending mining balance, which may be nil, at the current USD prices
+func GetPortfolio(publicKey string, pending *UnmineableInfo) (*Portfolio, error) {
+	solBalance, err := GetSOLBalance(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SOL balance: %v", err)
+	}
+	balances, err := GetWalletTokenBalances(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token balances: %v", err)
+	}
+
+	portfolio := &Portfolio{UpdatedAt: time.Now()}
+
+	portfolio.Holdings = append(portfolio.Holdings, valueHolding(SOL, solBalance))
+	for _, balance := range balances {
+		portfolio.Holdings = append(portfolio.Holdings, valueHolding(balance.Symbol, balance.Balance))
+	}
+	for _, holding := range portfolio.Holdings {
+		portfolio.WalletUSD += holding.ValueUSD
+	}
+
+	if pending != nil {
+		amount, err := strconv.ParseFloat(pending.Balance, 64)
+		if err == nil {
+			holding := valueHolding(pending.Coin, amount)
+			portfolio.Pending = &holding
+			portfolio.PendingUSD = holding.ValueUSD
+		}
+	}
+
+	portfolio.TotalUSD = portfolio.WalletUSD + portfolio.PendingUSD
+	if portfolio.TotalUSD > 0 {
+		for i := range portfolio.Holdings {
+			portfolio.Holdings[i].SharePct = portfolio.Holdings[i].ValueUSD / portfolio.TotalUSD * 100
+		}
+		if portfolio.Pending != nil {
+			portfolio.Pending.SharePct = portfolio.Pending.ValueUSD / portfolio.TotalUSD * 100
+		}
+	}
+
+	// Largest holdings first
+	sort.SliceStable(portfolio.Holdings, func(i, j int) bool {
+		return portfolio.Holdings[i].ValueUSD > portfolio.Holdings[j].ValueUSD
+	})
+
+	return portfolio, nil
+}
+
+func valueHolding(symbol string, amount float64) PortfolioHolding {
+	holding := PortfolioHolding{Symbol: symbol, Amount: amount}
+
+	price, err := GetTokenPrice(symbol)
+	if err != nil || price.PriceUSD == 0 {
+		return holding
+	}
+	holding.Priced = true
+	holding.StalePrice = price.Stale
+	holding.ValueUSD = amount * price.PriceUSD
+	holding.Change24h, holding.HasChange = GetPriceChangeUSD(symbol, 24*time.Hour)
+	return holding
+}
+
+// String formats the portfolio for the dashboard
+func (p *Portfolio) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("PORTFOLIO: $%.2f (wallet $%.2f + pending $%.2f)\n", p.TotalUSD, p.WalletUSD, p.PendingUSD))
+
+	var parts []string
+	for _, holding := range p.Holdings {
+		parts = append(parts, holding.String())
+	}
+	if p.Pending != nil {
+		parts = append(parts, "pending "+p.Pending.String())
+	}
+	sb.WriteString(strings.Join(parts, " | "))
+
+	return sb.String()
+}
+
+// String formats the holding as amount, value, share and 24h change
+func (h PortfolioHolding) String() string {
+	if !h.Priced {
+		return fmt.Sprintf("%s %s: no price", strconv.FormatFloat(h.Amount, 'f', 6, 64), h.Symbol)
+	}
+
+	change := "n/a"
+	if h.HasChange {
+		change = fmt.Sprintf("%+.2f%%", h.Change24h)
+	}
+	stale := ""
+	if h.StalePrice {
+		stale = " (stale)"
+	}
+	return fmt.Sprintf("%s %s: $%.2f%s %.1f%% 24h %s",
+		strconv.FormatFloat(h.Amount, 'f', 6, 64), h.Symbol, h.ValueUSD, stale, h.SharePct, change)
+}
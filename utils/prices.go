@@ -0,0 +1,380 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	priceUpdateInterval  = 30 * time.Minute
+	priceStaleAfter      = 2 * priceUpdateInterval
+	priceHistoryWindow   = 7 * 24 * time.Hour
+	priceHistoryFileName = "priceHistory.json"
+)
+
+// ErrPriceNotAvailable is returned when a token price was never fetched
+var ErrPriceNotAvailable = errors.New("Price not available")
+
+// TokenPrice is the last good price of a token in SOL and USD.
+// PriceUSD is 0 if Jupiter had no USDC price for the token.
+type TokenPrice struct {
+	Symbol    string
+	Mint      string
+	PriceSOL  float64
+	PriceUSD  float64
+	UpdatedAt time.Time
+	Stale     bool
+}
+
+// TokensPerSOL returns how many tokens one SOL buys at this price
+func (p TokenPrice) TokensPerSOL() float64 {
+	if p.PriceSOL == 0 {
+		return 0
+	}
+	return 1 / p.PriceSOL
+}
+
+// PricePoint is one entry of the rolling price history
+type PricePoint struct {
+	Time     time.Time `json:"time"`
+	PriceSOL float64   `json:"priceSOL"`
+	PriceUSD float64   `json:"priceUSD,omitempty"`
+}
+
+// priceService keeps the last good price of every tracked token together
+// with a rolling history that survives restarts
+type priceService struct {
+	mutex   sync.RWMutex
+	prices  map[string]TokenPrice
+	history map[string][]PricePoint
+	// updateMutex runs one update at a time, the ticker and RefreshPrices
+	// would otherwise fetch and write the history file together
+	updateMutex sync.Mutex
+}
+
+var prices = &priceService{
+	prices:  make(map[string]TokenPrice),
+	history: make(map[string][]PricePoint),
+}
+
+// GetTokenPrice returns the last good price of a token, SOL included
+func GetTokenPrice(tokenName string) (TokenPrice, error) {
+	return prices.get(tokenName)
+}
+
+// GetPriceHistory returns the recorded prices of a token within the window
+func GetPriceHistory(tokenName string, window time.Duration) []PricePoint {
+	return prices.historySince(tokenName, time.Now().Add(-window))
+}
+
+// GetPriceChangeUSD returns the USD price change of a token in percent over
+// the window, measured from the oldest recorded point within it. That point
+// has to be close to the start of the window, otherwise a few minutes of
+// history would pass for the whole window and there is no change.
+func GetPriceChangeUSD(tokenName string, window time.Duration) (float64, bool) {
+	price, err := GetTokenPrice(tokenName)
+	if err != nil || price.PriceUSD == 0 {
+		return 0, false
+	}
+
+	for _, point := range GetPriceHistory(tokenName, window) {
+		if point.PriceUSD <= 0 {
+			continue
+		}
+		if time.Since(point.Time) < window-priceUpdateInterval {
+			return 0, false
+		}
+		return (price.PriceUSD - point.PriceUSD) / point.PriceUSD * 100, true
+	}
+	return 0, false
+}
+
+// GetTokenExchangeAmount queries the amount of specified token that can be exchanged for a given amount of SOL
+func GetTokenExchangeAmount(solAmount string, tokenName string) (string, error) {
+	solAmountFloat, err := strconv.ParseFloat(solAmount, 64)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse SOL amount: %w", err)
+	}
+
+	price, err := GetTokenPrice(tokenName)
+	if err != nil {
+		return "", err
+	}
+
+	result := solAmountFloat * price.TokensPerSOL()
+
+	formattedResult := fmt.Sprintf("%.6f", result)
+	LogToFile(fmt.Sprintf("Calculated result for %s: %s", tokenName, formattedResult))
+
+	return formattedResult, nil
+}
+
+func GetSolExchangeAmount(tokenAmount string, tokenName string) (string, error) {
+	tokenAmountFloat, err := strconv.ParseFloat(tokenAmount, 64)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse token amount: %w", err)
+	}
+
+	price, err := GetTokenPrice(tokenName)
+	if err != nil {
+		return "", err
+	}
+
+	result := tokenAmountFloat * price.PriceSOL
+
+	formattedResult := fmt.Sprintf("%.9f", result) // 9 decimal places for SOL
+	LogToFile(fmt.Sprintf("Calculated SOL amount for %s %s: %s", tokenAmount, tokenName, formattedResult))
+
+	return formattedResult, nil
+}
+
+// RefreshPrices fetches the prices of all enabled tokens in the background
+func RefreshPrices() {
+	go prices.update()
+}
+
+func (s *priceService) start() {
+	s.loadHistory()
+	s.update()
+
+	// Update prices every 30 minutes
+	ticker := time.NewTicker(priceUpdateInterval)
+	go func() {
+		for range ticker.C {
+			s.update()
+		}
+	}()
+}
+
+func (s *priceService) get(tokenName string) (TokenPrice, error) {
+	if _, ok := lookupToken(tokenName); !ok && tokenName != SOL {
+		return TokenPrice{}, fmt.Errorf("Unknown token: %s", tokenName)
+	}
+
+	s.mutex.RLock()
+	price, ok := s.prices[tokenName]
+	s.mutex.RUnlock()
+
+	if !ok || price.PriceSOL == 0 {
+		return TokenPrice{}, ErrPriceNotAvailable
+	}
+
+	price.Stale = time.Since(price.UpdatedAt) > priceStaleAfter
+	if price.Stale {
+		LogToFile(fmt.Sprintf("Using stale price for %s from %s", tokenName, price.UpdatedAt.Format(time.RFC3339)))
+	}
+	return price, nil
+}
+
+func (s *priceService) historySince(tokenName string, since time.Time) []PricePoint {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var points []PricePoint
+	for _, point := range s.history[tokenName] {
+		if !point.Time.Before(since) {
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// update fetches the SOL prices of all tracked tokens in one request and
+// their USD prices, SOL included, in another. Tokens missing from the
+// SOL response keep their last good price.
+func (s *priceService) update() {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+
+	symbolsByMint := map[string]string{SOLMint: SOL}
+	var mints []string
+	for _, token := range GetEnabledTokens() {
+		symbolsByMint[token.Mint] = token.Symbol
+		mints = append(mints, token.Mint)
+	}
+
+	var fetched map[string]float64
+	if len(mints) > 0 {
+		var err error
+		fetched, err = fetchPrices(mints, SOLMint)
+		if err != nil {
+			LogToFile(fmt.Sprintf("Failed to update prices, keeping last good prices: %v", err))
+			return
+		}
+	} else {
+		fetched = make(map[string]float64)
+	}
+	fetched[SOLMint] = 1
+
+	// USD prices are optional, SOL prices drive the harvests
+	fetchedUSD, err := fetchPrices(append([]string{SOLMint}, mints...), "")
+	if err != nil {
+		LogToFile(fmt.Sprintf("Failed to update USD prices: %v", err))
+		fetchedUSD = make(map[string]float64)
+	}
+
+	now := time.Now()
+
+	s.mutex.Lock()
+	for mint, price := range fetched {
+		symbol := symbolsByMint[mint]
+		priceUSD := fetchedUSD[mint]
+		s.prices[symbol] = TokenPrice{
+			Symbol:    symbol,
+			Mint:      mint,
+			PriceSOL:  price,
+			PriceUSD:  priceUSD,
+			UpdatedAt: now,
+		}
+		s.history[symbol] = append(s.history[symbol], PricePoint{Time: now, PriceSOL: price, PriceUSD: priceUSD})
+		LogToFile(fmt.Sprintf("Fetched price for %s: %.12f SOL, %.8f USD", symbol, price, priceUSD))
+	}
+	s.trimHistory(now)
+	s.mutex.Unlock()
+
+	for _, mint := range mints {
+		if _, ok := fetched[mint]; !ok {
+			LogToFile(fmt.Sprintf("Price not found for %s, keeping last good price", symbolsByMint[mint]))
+		}
+	}
+
+	s.saveHistory()
+}
+
+// trimHistory drops points older than the history window. Caller holds the lock.
+func (s *priceService) trimHistory(now time.Time) {
+	cutoff := now.Add(-priceHistoryWindow)
+	for symbol, points := range s.history {
+		i := 0
+		for i < len(points) && points[i].Time.Before(cutoff) {
+			i++
+		}
+		s.history[symbol] = points[i:]
+	}
+}
+
+// loadHistory reads the price history from disk and seeds the last prices from it
+func (s *priceService) loadHistory() {
+	file, err := os.ReadFile(filepath.Join(GetExecutablePath(), priceHistoryFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			LogToFile(fmt.Sprintf("Failed to read price history: %v", err))
+		}
+		return
+	}
+
+	var history map[string][]PricePoint
+	if err := json.Unmarshal(file, &history); err != nil {
+		LogToFile(fmt.Sprintf("Failed to parse price history: %v", err))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for symbol, points := range history {
+		token, ok := lookupToken(symbol)
+		if symbol == SOL {
+			token, ok = TokenInfo{Mint: SOLMint, Symbol: SOL, Decimals: 9}, true
+		}
+		if !ok || len(points) == 0 {
+			continue
+		}
+		s.history[symbol] = points
+		last := points[len(points)-1]
+		s.prices[symbol] = TokenPrice{
+			Symbol:    symbol,
+			Mint:      token.Mint,
+			PriceSOL:  last.PriceSOL,
+			PriceUSD:  last.PriceUSD,
+			UpdatedAt: last.Time,
+		}
+	}
+	s.trimHistory(time.Now())
+}
+
+func (s *priceService) saveHistory() {
+	s.mutex.RLock()
+	file, err := json.MarshalIndent(s.history, "", "  ")
+	s.mutex.RUnlock()
+	if err != nil {
+		LogToFile(fmt.Sprintf("Failed to marshal price history: %v", err))
+		return
+	}
+
+	// Write a temporary file and rename it over the history, so a crash
+	// mid-write never leaves a truncated file behind
+	path := filepath.Join(GetExecutablePath(), priceHistoryFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, file, 0644); err != nil {
+		LogToFile(fmt.Sprintf("Failed to write price history: %v", err))
+		return
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		LogToFile(fmt.Sprintf("Failed to replace price history: %v", err))
+		os.Remove(tmpPath)
+	}
+}
+
+// fetchPrices queries the price of all mints in a single Jupiter Price v2
+// request. Without vsToken the prices are in USDC.
+func fetchPrices(mints []string, vsToken string) (map[string]float64, error) {
+	LogToFile(fmt.Sprintf("Fetching prices for %d tokens", len(mints)))
+
+	apiURL := fmt.Sprintf("%s?ids=%s", JupiterPriceURL, strings.Join(mints, ","))
+	if vsToken != "" {
+		apiURL += "&vsToken=" + vsToken
+	}
+
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prices from Jupiter API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Jupiter price API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var priceResponse struct {
+		Data map[string]*struct {
+			ID    string `json:"id"`
+			Type  string `json:"type"`
+			Price string `json:"price"`
+		} `json:"data"`
+		TimeTaken float64 `json:"timeTaken"`
+	}
+	if err := json.Unmarshal(body, &priceResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
+	}
+
+	result := make(map[string]float64)
+	for mint, priceData := range priceResponse.Data {
+		// Unknown tokens come back as null
+		if priceData == nil {
+			continue
+		}
+		price, err := strconv.ParseFloat(priceData.Price, 64)
+		if err != nil || price <= 0 {
+			LogToFile(fmt.Sprintf("Invalid price for %s: %q", mint, priceData.Price))
+			continue
+		}
+		result[mint] = price
+	}
+
+	return result, nil
+}
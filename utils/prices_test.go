@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetPriceChangeUSD(t *testing.T) {
+	now := time.Now()
+	window := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		history    []PricePoint
+		wantChange float64
+		wantOK     bool
+	}{
+		{
+			name:    "no history",
+			history: nil,
+		},
+		{
+			name:    "history of the last hour only",
+			history: []PricePoint{{Time: now.Add(-time.Hour), PriceUSD: 100}},
+		},
+		{
+			name: "first USD price too recent",
+			history: []PricePoint{
+				{Time: now.Add(-23 * time.Hour), PriceSOL: 1},
+				{Time: now.Add(-2 * time.Hour), PriceUSD: 100},
+			},
+		},
+		{
+			name:       "point at the start of the window",
+			history:    []PricePoint{{Time: now.Add(-window + time.Minute), PriceUSD: 100}},
+			wantChange: 10,
+			wantOK:     true,
+		},
+		{
+			name:       "point one update interval into the window",
+			history:    []PricePoint{{Time: now.Add(-window + priceUpdateInterval), PriceUSD: 200}},
+			wantChange: -45,
+			wantOK:     true,
+		},
+		{
+			name: "points older than the window are ignored",
+			history: []PricePoint{
+				{Time: now.Add(-48 * time.Hour), PriceUSD: 50},
+				{Time: now.Add(-3 * time.Hour), PriceUSD: 100},
+			},
+		},
+	}
+
+	saved := prices
+	defer func() { prices = saved }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices = &priceService{
+				prices: map[string]TokenPrice{
+					SOL: {Symbol: SOL, Mint: SOLMint, PriceSOL: 1, PriceUSD: 110, UpdatedAt: now},
+				},
+				history: map[string][]PricePoint{SOL: tt.history},
+			}
+
+			change, ok := GetPriceChangeUSD(SOL, window)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && math.Abs(change-tt.wantChange) > 1e-9 {
+				t.Fatalf("change = %v, want %v", change, tt.wantChange)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+const (
+	// How often an unconfirmed transaction is rebroadcast
+	rebroadcastInterval = 2 * time.Second
+	// Overall deadline for a single swap, including re-quotes
+	swapTimeout = 3 * time.Minute
+	// How many times a swap is re-quoted after its blockhash expired
+	maxSwapAttempts = 3
+	// Time for the last status check of a swap whose deadline passed
+	finalStatusTimeout = 30 * time.Second
+	// A transaction can't land this long after it was signed, its blockhash
+	// of about 150 blocks has expired by then
+	blockhashLifetime = 2 * time.Minute
+)
+
+// ErrBlockhashExpired is returned when a transaction's blockhash expired and
+// the transaction is known not to have landed, so it is safe to re-sign
+var ErrBlockhashExpired = errors.New("transaction blockhash expired before confirmation")
+
+// ErrTransactionStatusUnknown is returned when a transaction was sent but
+// it couldn't be found out whether it landed
+var ErrTransactionStatusUnknown = errors.New("transaction status unknown")
+
+// ErrSwapOutcomeUnknown is returned when a swap was sent and neither
+// confirmed nor ruled out. It may still execute, so it must not be retried.
+var ErrSwapOutcomeUnknown = errors.New("swap outcome unknown, it may still execute and must not be retried")
+
+// sendAndConfirmTransaction broadcasts a signed transaction and keeps
+// rebroadcasting it until it is confirmed, fails or its blockhash expires
+func sendAndConfirmTransaction(ctx context.Context, client *rpc.Client, tx *solana.Transaction, lastValidBlockHeight uint64) (solana.Signature, error) {
+	if len(tx.Signatures) == 0 {
+		return solana.Signature{}, fmt.Errorf("transaction is not signed")
+	}
+	sig := tx.Signatures[0]
+
+	// Rebroadcasting is done here, not by the RPC node
+	noRetries := uint(0)
+
+	// First broadcast with preflight checks
+	_, err := client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		SkipPreflight:       false,
+		PreflightCommitment: rpc.CommitmentConfirmed,
+		MaxRetries:          &noRetries,
+	})
+	if err != nil {
+		var rpcErr *jsonrpc.RPCError
+		if errors.As(err, &rpcErr) {
+			// Rejected by preflight, the transaction was not forwarded
+			return sig, fmt.Errorf("failed to send transaction: %v", err)
+		}
+		// The request may still have reached the node, keep watching the signature
+		LogToFile(fmt.Sprintf("Error sending transaction %s, will rebroadcast: %v", sig, err))
+	} else {
+		LogToFile(fmt.Sprintf("Transaction sent: %s", sig))
+	}
+
+	ticker := time.NewTicker(rebroadcastInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return sig, fmt.Errorf("transaction %s not confirmed: %w: %w", sig, ErrTransactionStatusUnknown, ctx.Err())
+		case <-ticker.C:
+		}
+
+		status, err := getSignatureStatus(ctx, client, sig, false)
+		if err == nil && status != nil {
+			if status.Err != nil {
+				return sig, fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+			}
+			if isConfirmed(status) {
+				LogToFile(fmt.Sprintf("Transaction confirmed: %s", sig))
+				return sig, nil
+			}
+			// Processed but not yet confirmed, keep waiting
+			continue
+		}
+
+		if blockhashExpired(ctx, client, tx, lastValidBlockHeight) {
+			// Final check through the ledger history, it may have landed in the meantime
+			status, err := getSignatureStatus(ctx, client, sig, true)
+			if err != nil {
+				return sig, fmt.Errorf("unable to determine status of transaction %s: %w: %v", sig, ErrTransactionStatusUnknown, err)
+			}
+			if status == nil {
+				LogToFile(fmt.Sprintf("Transaction %s expired", sig))
+				return sig, ErrBlockhashExpired
+			}
+			if status.Err != nil {
+				return sig, fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+			}
+			if isConfirmed(status) {
+				LogToFile(fmt.Sprintf("Transaction confirmed: %s", sig))
+				return sig, nil
+			}
+			continue
+		}
+
+		_, err = client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+			SkipPreflight: true,
+			MaxRetries:    &noRetries,
+		})
+		if err != nil {
+			LogToFile(fmt.Sprintf("Error rebroadcasting transaction %s: %v", sig, err))
+		}
+	}
+}
+
+// blockhashExpired reports whether the transaction can no longer land.
+// Without a known last valid block height the blockhash itself is checked.
+func blockhashExpired(ctx context.Context, client *rpc.Client, tx *solana.Transaction, lastValidBlockHeight uint64) bool {
+	if lastValidBlockHeight == 0 {
+		valid, err := client.IsBlockhashValid(ctx, tx.Message.RecentBlockhash, rpc.CommitmentConfirmed)
+		return err == nil && !valid.Value
+	}
+
+	blockHeight, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+	return err == nil && blockHeight > lastValidBlockHeight
+}
+
+// findLandedSignature returns the first of the given signatures that landed
+// successfully. It is used before re-signing, so a swap never executes twice.
+func findLandedSignature(ctx context.Context, client *rpc.Client, signatures []solana.Signature) (solana.Signature, bool, error) {
+	for _, sig := range signatures {
+		status, err := getSignatureStatus(ctx, client, sig, true)
+		if err != nil {
+			return solana.Signature{}, false, err
+		}
+		if status != nil && status.Err == nil {
+			return sig, true, nil
+		}
+	}
+	return solana.Signature{}, false, nil
+}
+
+// getSignatureStatus returns nil without error when the signature is unknown
+func getSignatureStatus(ctx context.Context, client *rpc.Client, sig solana.Signature, searchHistory bool) (*rpc.SignatureStatusesResult, error) {
+	out, err := client.GetSignatureStatuses(ctx, searchHistory, sig)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(out.Value) == 0 {
+		return nil, nil
+	}
+	return out.Value[0], nil
+}
+
+func isConfirmed(status *rpc.SignatureStatusesResult) bool {
+	return status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed ||
+		status.ConfirmationStatus == rpc.ConfirmationStatusFinalized
+}
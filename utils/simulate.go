@@ -0,0 +1,353 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TokenBalanceChange is the expected change of a wallet token account
+type TokenBalanceChange struct {
+	// The token account, one mint may have several
+	Account   string
+	Mint      string
+	Symbol    string
+	Change    float64
+	RawChange int64
+	Decimals  int
+	// The balance couldn't be read, Change and RawChange are meaningless
+	Unknown bool
+}
+
+// SimulationPreview describes what a transaction is expected to do on-chain
+type SimulationPreview struct {
+	SOLChange      float64
+	LamportsChange int64
+	TokenChanges   []TokenBalanceChange
+	// Rent moved from the wallet into token accounts it creates, less the
+	// rent of the ones it closes. Wrapped SOL accounts are not included.
+	RentLamports  int64
+	FeeLamports   uint64
+	UnitsConsumed uint64
+	Logs          []string
+	Err           string
+}
+
+// ConfirmTransactionFunc receives the simulation preview before a transaction
+// is signed. Returning false cancels the transaction.
+type ConfirmTransactionFunc func(preview *SimulationPreview) bool
+
+// Failed reports whether the simulated transaction returned an error
+func (p *SimulationPreview) Failed() bool {
+	return p.Err != ""
+}
+
+// String formats the preview for display in a confirmation dialog
+func (p *SimulationPreview) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("TRANSACTION PREVIEW\n\n")
+	sb.WriteString(fmt.Sprintf("SOL: %+.9f\n", p.SOLChange))
+	for _, change := range p.TokenChanges {
+		if change.Unknown {
+			sb.WriteString(fmt.Sprintf("%s: unknown\n", change.Symbol))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%s: %+.6f\n", change.Symbol, change.Change))
+	}
+	sb.WriteString(fmt.Sprintf("Fee: %.9f SOL\n", float64(p.FeeLamports)/1e9))
+	sb.WriteString(fmt.Sprintf("Compute units: %d\n", p.UnitsConsumed))
+
+	if p.Failed() {
+		sb.WriteString("\nSimulation error: " + p.Err + "\n")
+	}
+
+	if len(p.Logs) > 0 {
+		sb.WriteString("\nProgram logs:\n")
+		logs := p.Logs
+		// Keep the dialog readable, the full logs go to the debug file
+		if len(logs) > 10 {
+			logs = logs[len(logs)-10:]
+		}
+		sb.WriteString(strings.Join(logs, "\n"))
+	}
+
+	return sb.String()
+}
+
+// The simulation returns at most this many accounts
+const maxSimulationAccounts = 100
+
+// SimulateTransaction runs the transaction through simulateTransaction and
+// compares the resulting SOL and token balances of the owner with the current ones
+func SimulateTransaction(client *rpc.Client, tx *solana.Transaction, owner solana.PublicKey) (*SimulationPreview, error) {
+	// 1. Watch the owner account, the associated token accounts of all
+	// registered tokens, which the transaction may create, and every token
+	// account the owner already has under either token program
+	watched := []solana.PublicKey{owner}
+	watchedMints := []string{""}
+	isWatched := map[solana.PublicKey]bool{owner: true}
+	for _, token := range GetTokens() {
+		mint := token.Mint
+		mintKey, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			continue
+		}
+		ata, _, err := solana.FindAssociatedTokenAddress(owner, mintKey)
+		if err != nil {
+			continue
+		}
+		watched = append(watched, ata)
+		watchedMints = append(watchedMints, mint)
+		isWatched[ata] = true
+	}
+	for _, programID := range []solana.PublicKey{solana.TokenProgramID, solana.Token2022ProgramID} {
+		accounts, err := client.GetTokenAccountsByOwner(context.TODO(), owner,
+			&rpc.GetTokenAccountsConfig{ProgramId: programID.ToPointer()},
+			&rpc.GetTokenAccountsOpts{Encoding: solana.EncodingJSONParsed, Commitment: rpc.CommitmentConfirmed})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token accounts: %v", err)
+		}
+		for _, account := range accounts.Value {
+			if isWatched[account.Pubkey] {
+				continue
+			}
+			mint, err := tokenAccountMint(&account.Account)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read token account %s: %v", account.Pubkey, err)
+			}
+			watched = append(watched, account.Pubkey)
+			watchedMints = append(watchedMints, mint)
+			isWatched[account.Pubkey] = true
+		}
+	}
+	if len(watched) > maxSimulationAccounts {
+		return nil, fmt.Errorf("wallet has %d token accounts, at most %d can be checked in a simulation", len(watched)-1, maxSimulationAccounts-1)
+	}
+
+	before, err := client.GetMultipleAccountsWithOpts(context.TODO(), watched, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingJSONParsed,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current balances: %v", err)
+	}
+
+	simResp, err := client.SimulateTransactionWithOpts(context.TODO(), tx, &rpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		Commitment:             rpc.CommitmentConfirmed,
+		ReplaceRecentBlockhash: true,
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingJSONParsed,
+			Addresses: watched,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %v", err)
+	}
+	if simResp.Value == nil {
+		return nil, fmt.Errorf("empty simulation result")
+	}
+
+	sim := simResp.Value
+	preview := &SimulationPreview{
+		Logs: sim.Logs,
+	}
+	if sim.Err != nil {
+		preview.Err = fmt.Sprintf("%v", sim.Err)
+	}
+	if sim.UnitsConsumed != nil {
+		preview.UnitsConsumed = *sim.UnitsConsumed
+	}
+
+	// Balance changes are only meaningful if the simulation executed
+	if !preview.Failed() && len(sim.Accounts) == len(watched) && len(before.Value) == len(watched) {
+		preview.LamportsChange = accountLamports(sim.Accounts[0]) - accountLamports(before.Value[0])
+		preview.SOLChange = float64(preview.LamportsChange) / 1e9
+
+		for i := 1; i < len(watched); i++ {
+			// A wrapped SOL account holds the swapped SOL on top of its rent
+			if watchedMints[i] != SOLMint {
+				preview.RentLamports += createdAccountRent(before.Value[i], sim.Accounts[i])
+			}
+
+			after, decimals, err := tokenAccountAmount(sim.Accounts[i])
+			current, currentDecimals, currentErr := tokenAccountAmount(before.Value[i])
+			if err == nil {
+				err = currentErr
+			}
+			if err != nil {
+				LogToFile(fmt.Sprintf("Failed to read %s balance of the simulation: %v", tokenChangeSymbol(watchedMints[i]), err))
+				preview.TokenChanges = append(preview.TokenChanges, TokenBalanceChange{
+					Account: watched[i].String(),
+					Mint:    watchedMints[i],
+					Symbol:  tokenChangeSymbol(watchedMints[i]),
+					Unknown: true,
+				})
+				continue
+			}
+			if decimals == 0 {
+				decimals = currentDecimals
+			}
+			change := after - current
+			if change == 0 {
+				continue
+			}
+			preview.TokenChanges = append(preview.TokenChanges, TokenBalanceChange{
+				Account:   watched[i].String(),
+				Mint:      watchedMints[i],
+				Symbol:    tokenChangeSymbol(watchedMints[i]),
+				Change:    float64(change) / math.Pow10(decimals),
+				RawChange: change,
+				Decimals:  decimals,
+			})
+		}
+	}
+
+	// Fee for the message at the current blockhash
+	messageData, err := tx.Message.MarshalBinary()
+	if err == nil {
+		feeResp, err := client.GetFeeForMessage(context.TODO(), base64.StdEncoding.EncodeToString(messageData), rpc.CommitmentConfirmed)
+		if err != nil {
+			LogToFile(fmt.Sprintf("Failed to get fee for message: %v", err))
+		} else if feeResp.Value != nil {
+			preview.FeeLamports = *feeResp.Value
+		}
+	}
+
+	LogToFile(fmt.Sprintf("Simulation preview: %+v", *preview))
+	return preview, nil
+}
+
+// TokenChange returns the expected change for the given mint, summed over
+// all token accounts of the mint, if any
+func (p *SimulationPreview) TokenChange(mint string) (TokenBalanceChange, bool) {
+	var total TokenBalanceChange
+	found := false
+	for _, change := range p.TokenChanges {
+		if change.Mint != mint {
+			continue
+		}
+		if !found {
+			total = TokenBalanceChange{Mint: change.Mint, Symbol: change.Symbol, Decimals: change.Decimals}
+			found = true
+		}
+		total.Change += change.Change
+		total.RawChange += change.RawChange
+		total.Unknown = total.Unknown || change.Unknown
+	}
+	return total, found
+}
+
+// tokenChangeSymbol names a mint in the preview, unregistered tokens by their mint
+func tokenChangeSymbol(mint string) string {
+	if token, ok := lookupTokenByMint(mint); ok {
+		return token.Symbol
+	}
+	if mint == SOLMint {
+		return "wSOL"
+	}
+	return mint
+}
+
+// confirmTransaction simulates the transaction and asks confirm for approval
+func confirmTransaction(client *rpc.Client, tx *solana.Transaction, owner solana.PublicKey, confirm ConfirmTransactionFunc) error {
+	preview, err := SimulateTransaction(client, tx, owner)
+	if err != nil {
+		return err
+	}
+	return approvePreview(preview, confirm)
+}
+
+// approvePreview asks confirm for approval of a simulated transaction.
+// A nil confirm approves every transaction whose simulation succeeded.
+func approvePreview(preview *SimulationPreview, confirm ConfirmTransactionFunc) error {
+	if confirm == nil {
+		if preview.Failed() {
+			return fmt.Errorf("transaction simulation failed: %s", preview.Err)
+		}
+		return nil
+	}
+
+	if !confirm(preview) {
+		return fmt.Errorf("transaction cancelled by user")
+	}
+	return nil
+}
+
+func accountLamports(account *rpc.Account) int64 {
+	if account == nil {
+		return 0
+	}
+	return int64(account.Lamports)
+}
+
+// createdAccountRent is the rent put into an account the transaction
+// creates, negative for the rent returned by an account it closes
+func createdAccountRent(before *rpc.Account, after *rpc.Account) int64 {
+	switch {
+	case before == nil && after != nil:
+		return accountLamports(after)
+	case before != nil && after == nil:
+		return -accountLamports(before)
+	default:
+		return 0
+	}
+}
+
+// tokenAccountMint returns the mint of a jsonParsed token account
+func tokenAccountMint(account *rpc.Account) (string, error) {
+	if account == nil || account.Data == nil {
+		return "", fmt.Errorf("no account data")
+	}
+
+	var parsed struct {
+		Parsed struct {
+			Info struct {
+				Mint string `json:"mint"`
+			} `json:"info"`
+		} `json:"parsed"`
+	}
+	if err := json.Unmarshal(account.Data.GetRawJSON(), &parsed); err != nil {
+		return "", fmt.Errorf("invalid token account data: %v", err)
+	}
+	if parsed.Parsed.Info.Mint == "" {
+		return "", fmt.Errorf("token account has no mint")
+	}
+	return parsed.Parsed.Info.Mint, nil
+}
+
+// tokenAccountAmount returns the raw amount and decimals of a jsonParsed
+// token account, 0 for an account that doesn't exist
+func tokenAccountAmount(account *rpc.Account) (int64, int, error) {
+	if account == nil || account.Data == nil {
+		return 0, 0, nil
+	}
+
+	var parsed struct {
+		Parsed struct {
+			Info struct {
+				TokenAmount struct {
+					Amount   string `json:"amount"`
+					Decimals int    `json:"decimals"`
+				} `json:"tokenAmount"`
+			} `json:"info"`
+		} `json:"parsed"`
+	}
+	if err := json.Unmarshal(account.Data.GetRawJSON(), &parsed); err != nil {
+		return 0, 0, fmt.Errorf("invalid token account data: %v", err)
+	}
+
+	amount, err := strconv.ParseInt(parsed.Parsed.Info.TokenAmount.Amount, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid token amount %q: %v", parsed.Parsed.Info.TokenAmount.Amount, err)
+	}
+	return amount, parsed.Parsed.Info.TokenAmount.Decimals, nil
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
@@ -11,19 +13,25 @@ import (
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
-// BurnToken burns a specified amount of a given token
-func BurnToken(amount string, token string, memoText string) (string, error) {
+const SolanaRPCURL = "https://api.mainnet-beta.solana.com"
+
+// BurnToken burns a specified amount of a given token.
+// The burn is simulated first and confirm decides whether it gets signed.
+func BurnToken(amount string, token string, memoText string, confirm ConfirmTransactionFunc) (string, error) {
 	// Initialize Solana client
-	client := rpc.New("https://api.mainnet-beta.solana.com")
+	client := rpc.New(SolanaRPCURL)
 
 	// Define the token mint address and the associated token account
-	// Get token mint address from tokenAddresses map
-	mintAddress, exists := tokenAddresses[token]
+	// Get token mint address from the token registry
+	tokenInfo, exists := lookupToken(token)
 	if !exists {
 		LogToFile(fmt.Sprintf("Error: token %s not found in supported tokens", token))
 		return "", fmt.Errorf("token %s not found in supported tokens", token)
 	}
-	tokenMintAddress := solana.MustPublicKeyFromBase58(mintAddress)
+	tokenMintAddress, err := solana.PublicKeyFromBase58(tokenInfo.Mint)
+	if err != nil {
+		return "", fmt.Errorf("invalid mint address for %s: %v", token, err)
+	}
 
 	// Get owner's public key
 	owner, err := solana.PrivateKeyFromBase58(getPrivateKey())
@@ -60,7 +68,7 @@ func BurnToken(amount string, token string, memoText string) (string, error) {
 	}
 
 	// Convert to smallest unit (multiply by 10^6)
-	amountToBurn := uint64(amountFloat * 1_000_000)
+	amountToBurn := uint64(amountFloat * math.Pow10(tokenInfo.Decimals))
 
 	// LogToFile("7777")
 	// // Convert balance to uint64 for comparison
@@ -96,8 +104,8 @@ func BurnToken(amount string, token string, memoText string) (string, error) {
 
 	// Create the burn instruction
 	burnInstruction := spl_token.NewBurnCheckedInstruction(
-		amountToBurn, // amount
-		6,            // decimals
+		amountToBurn,              // amount
+		uint8(tokenInfo.Decimals), // decimals
 		tokenAccountAddress,
 		tokenMintAddress,
 		owner.PublicKey(),
@@ -145,43 +153,62 @@ func BurnToken(amount string, token string, memoText string) (string, error) {
 		break
 	}
 
-	// Sign the transaction
-	_, err = tx.Sign(
-		func(key solana.PublicKey) *solana.PrivateKey {
-			if key.Equals(owner.PublicKey()) {
-				return &owner
-			}
-			return nil
-		},
-	)
-	if err != nil {
-		LogToFile(fmt.Sprintf("Error: failed to sign transaction: %v", err))
-		return "", fmt.Errorf("failed to sign transaction: %v", err)
+	// Simulate the transaction and let the user confirm it
+	if err := confirmTransaction(client, tx, owner.PublicKey(), confirm); err != nil {
+		LogToFile(fmt.Sprintf("Error: burn not confirmed: %v", err))
+		return "", err
 	}
 
-	// Send the transaction
+	ctx, cancel := context.WithTimeout(context.Background(), swapTimeout)
+	defer cancel()
+
+	// Sign and send the transaction, re-signing with a fresh blockhash if it expires
 	var sig solana.Signature
+	var sentSignatures []solana.Signature
 	for i := 0; i < maxRetries; i++ {
-		var err error
-		var maxRetriesUint uint = uint(maxRetries)
-		sig, err = client.SendTransactionWithOpts(context.TODO(), tx, rpc.TransactionOpts{
-			SkipPreflight:       false,
-			PreflightCommitment: rpc.CommitmentFinalized,
-			MaxRetries:          &maxRetriesUint,
-		})
+		_, err = tx.Sign(
+			func(key solana.PublicKey) *solana.PrivateKey {
+				if key.Equals(owner.PublicKey()) {
+					return &owner
+				}
+				return nil
+			},
+		)
 		if err != nil {
-			LogToFile(fmt.Sprintf("Attempt %d: Error sending transaction: %v", i+1, err))
+			LogToFile(fmt.Sprintf("Error: failed to sign transaction: %v", err))
+			return "", fmt.Errorf("failed to sign transaction: %v", err)
+		}
+
+		sig, err = sendAndConfirmTransaction(ctx, client, tx, recentBlockhash.Value.LastValidBlockHeight)
+		if errors.Is(err, ErrBlockhashExpired) {
+			sentSignatures = append(sentSignatures, sig)
+
+			// Make sure no earlier attempt landed before burning again
+			landedSig, landed, err := findLandedSignature(ctx, client, sentSignatures)
+			if err != nil {
+				return "", fmt.Errorf("unable to check earlier burn attempts, not retrying: %v", err)
+			}
+			if landed {
+				sig = landedSig
+				break
+			}
+
+			LogToFile(fmt.Sprintf("Attempt %d: burn transaction expired", i+1))
 			if i == maxRetries-1 {
-				return "", fmt.Errorf("failed to send transaction after %d attempts: %v", maxRetries, err)
+				return "", fmt.Errorf("burn not confirmed after %d attempts", maxRetries)
 			}
-			// Get new blockhash and recreate transaction if needed
-			recentBlockhash, err = client.GetLatestBlockhash(context.TODO(), rpc.CommitmentFinalized)
-			if err == nil {
-				tx.Message.RecentBlockhash = recentBlockhash.Value.Blockhash
+
+			recentBlockhash, err = client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+			if err != nil {
+				return "", fmt.Errorf("failed to get recent blockhash: %v", err)
 			}
-			time.Sleep(time.Second * 2)
+			tx.Message.RecentBlockhash = recentBlockhash.Value.Blockhash
 			continue
 		}
+		if err != nil {
+			LogToFile(fmt.Sprintf("Attempt %d: Error sending transaction: %v", i+1, err))
+			return "", fmt.Errorf("failed to send transaction: %v", err)
+		}
 		break
 	}
 
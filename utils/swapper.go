@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Swap backends selectable in the config
+const (
+	SwapBackendJupiter = "jupiter"
+	SwapBackendStub    = "stub"
+)
+
+// Swapper is a swap aggregator backend. Amounts are in the smallest unit of
+// the mint, as in the quotes.
+type Swapper interface {
+	Name() string
+	// Offline backends build transactions that are verified but never signed or sent
+	Offline() bool
+	Quote(inputMint string, outputMint string, amount string, swapMode string, slippageBps int) (*QuoteResponse, error)
+	SwapTransaction(quote *QuoteResponse, userPublicKey string) (*SwapResponse, error)
+}
+
+var (
+	swapper      Swapper
+	swapperMutex sync.Mutex
+)
+
+// NewSwapperFromConfig creates the swap backend selected in the config
+func NewSwapperFromConfig(config SolXENConfig) (Swapper, error) {
+	switch config.SwapBackend {
+	case "", SwapBackendJupiter:
+		return NewJupiterSwapper(config.JupiterBaseURL, config.JupiterAPIKey), nil
+	case SwapBackendStub:
+		return NewStubSwapper(), nil
+	default:
+		return nil, fmt.Errorf("unknown swap backend: %s", config.SwapBackend)
+	}
+}
+
+// getSwapper returns the swap backend, creating it from the config on first use
+func getSwapper() Swapper {
+	swapperMutex.Lock()
+	defer swapperMutex.Unlock()
+
+	if swapper == nil {
+		config, err := ReadSolXENConfigFile()
+		if err != nil {
+			LogToFile(fmt.Sprintf("Failed to read config, using Jupiter: %v", err))
+		}
+		swapper, err = NewSwapperFromConfig(config)
+		if err != nil {
+			LogToFile(fmt.Sprintf("%v, using Jupiter", err))
+			swapper = NewJupiterSwapper(config.JupiterBaseURL, config.JupiterAPIKey)
+		}
+		LogToFile(fmt.Sprintf("Swap backend: %s", swapper.Name()))
+	}
+	return swapper
+}
+
+// offlineSwapResult checks the transaction of an offline backend against the
+// signing policy and returns the quoted amounts without sending anything
+func offlineSwapResult(client *rpc.Client, swapResp *SwapResponse, owner solana.PublicKey, quote *QuoteResponse) (*SwapResult, error) {
+	tx, err := decodeSwapTransaction(swapResp)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySwapTransaction(client, tx, owner, quote); err != nil {
+		return nil, fmt.Errorf("swap transaction rejected: %v", err)
+	}
+
+	result := swapResultFromQuote("offline", quote)
+	LogToFile(fmt.Sprintf("Offline swap result: %s", result))
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SwapResult is what a confirmed swap actually did on-chain
+type SwapResult struct {
+	Signature  string
+	InputMint  string
+	OutputMint string
+	AmountIn   float64
+	AmountOut  float64
+	QuotedOut  float64
+	FeeSOL     float64
+	// Rent of the token accounts the swap created, less the ones it closed.
+	// Wrapped SOL accounts are not included.
+	RentSOL     float64
+	SlippagePct float64
+	FromQuote   bool
+	ConfirmedAt time.Time
+}
+
+// String formats the result for the harvest log
+func (r *SwapResult) String() string {
+	source := "on-chain"
+	if r.FromQuote {
+		source = "quoted"
+	}
+	return fmt.Sprintf("%s -> %s (%s, quoted %s, slippage %.2f%%, fee %.9f SOL, rent %.9f SOL, tx %s)",
+		formatSwapAmount(r.AmountIn, r.InputMint), formatSwapAmount(r.AmountOut, r.OutputMint), source,
+		formatSwapAmount(r.QuotedOut, r.OutputMint), r.SlippagePct, r.FeeSOL, r.RentSOL, r.Signature)
+}
+
+func formatSwapAmount(amount float64, mint string) string {
+	symbol := getTokenSymbol(mint)
+	if mint == SOLMint {
+		symbol = "SOL"
+	}
+	return strconv.FormatFloat(amount, 'f', -1, 64) + " " + symbol
+}
+
+// getSwapResult reads the confirmed swap transaction and computes the real
+// amounts from the owner's balance changes. If the transaction can't be
+// fetched, the quote amounts are returned instead.
+func getSwapResult(ctx context.Context, client *rpc.Client, sig solana.Signature, owner solana.PublicKey, quote *QuoteResponse) *SwapResult {
+	result := swapResultFromQuote(sig.String(), quote)
+
+	tx, err := getConfirmedTransaction(ctx, client, sig)
+	if err != nil || tx == nil || tx.Meta == nil {
+		LogToFile(fmt.Sprintf("Unable to fetch swap transaction %s, using quote: %v", sig, err))
+		return result
+	}
+	result.FromQuote = false
+
+	quotedIn, _ := strconv.ParseFloat(quote.InAmount, 64)
+	inDecimals := mintDecimals(quote.InputMint)
+
+	meta := tx.Meta
+	result.FeeSOL = float64(meta.Fee) / 1e9
+	if tx.BlockTime != nil {
+		result.ConfirmedAt = tx.BlockTime.Time()
+	}
+
+	// The owner is the fee payer, so it is always the first account. The
+	// rent of new token accounts stays in the wallet and is not swapped.
+	rentLamports := swapAccountRent(meta, owner)
+	result.RentSOL = float64(rentLamports) / 1e9
+	var lamportsChange int64
+	if len(meta.PreBalances) > 0 && len(meta.PostBalances) > 0 {
+		lamportsChange = int64(meta.PostBalances[0]) - int64(meta.PreBalances[0]) + int64(meta.Fee) + rentLamports
+	}
+
+	amountChange := func(mint string) float64 {
+		if mint == SOLMint {
+			return float64(lamportsChange) / 1e9
+		}
+		return ownerTokenBalance(meta.PostTokenBalances, owner, mint) - ownerTokenBalance(meta.PreTokenBalances, owner, mint)
+	}
+
+	result.AmountIn = -amountChange(quote.InputMint)
+	result.AmountOut = amountChange(quote.OutputMint)
+
+	// ExactOut swaps slip on the input side
+	if quote.SwapMode == SwapModeExactOut {
+		if quotedIn > 0 {
+			quotedInAmount := quotedIn / math.Pow10(inDecimals)
+			result.SlippagePct = (result.AmountIn - quotedInAmount) / quotedInAmount * 100
+		}
+	} else if result.QuotedOut > 0 {
+		result.SlippagePct = (result.QuotedOut - result.AmountOut) / result.QuotedOut * 100
+	}
+
+	LogToFile(fmt.Sprintf("Swap result: %s", result))
+	return result
+}
+
+// swapResultFromQuote returns the amounts the quote promised
+func swapResultFromQuote(signature string, quote *QuoteResponse) *SwapResult {
+	quotedIn, _ := strconv.ParseFloat(quote.InAmount, 64)
+	quotedOut, _ := strconv.ParseFloat(quote.OutAmount, 64)
+	quotedOut /= math.Pow10(mintDecimals(quote.OutputMint))
+
+	return &SwapResult{
+		Signature:   signature,
+		InputMint:   quote.InputMint,
+		OutputMint:  quote.OutputMint,
+		AmountIn:    quotedIn / math.Pow10(mintDecimals(quote.InputMint)),
+		AmountOut:   quotedOut,
+		QuotedOut:   quotedOut,
+		FromQuote:   true,
+		ConfirmedAt: time.Now(),
+	}
+}
+
+// getConfirmedTransaction fetches a transaction that was just confirmed,
+// retrying briefly since RPC nodes may not serve it immediately
+func getConfirmedTransaction(ctx context.Context, client *rpc.Client, sig solana.Signature) (*rpc.GetTransactionResult, error) {
+	maxVersion := uint64(0)
+	var lastErr error
+	for i := 0; i < 5; i++ {
+		tx, err := client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+			Encoding:                       solana.EncodingBase64,
+			Commitment:                     rpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &maxVersion,
+		})
+		if err == nil && tx != nil {
+			return tx, nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+	return nil, lastErr
+}
+
+// swapAccountRent returns the lamports moved into token accounts of the owner
+// that the transaction created, less those of the ones it closed, like the
+// simulation does for the preview. Wrapped SOL accounts are left out.
+func swapAccountRent(meta *rpc.TransactionMeta, owner solana.PublicKey) int64 {
+	lamports := func(balances []uint64, index uint16) uint64 {
+		if int(index) >= len(balances) {
+			return 0
+		}
+		return balances[index]
+	}
+	isOwnerAccount := func(balance rpc.TokenBalance) bool {
+		return balance.Owner != nil && balance.Owner.Equals(owner) && balance.Mint.String() != SOLMint
+	}
+
+	var rent int64
+	for _, balance := range meta.PostTokenBalances {
+		if isOwnerAccount(balance) && lamports(meta.PreBalances, balance.AccountIndex) == 0 {
+			rent += int64(lamports(meta.PostBalances, balance.AccountIndex))
+		}
+	}
+	for _, balance := range meta.PreTokenBalances {
+		if isOwnerAccount(balance) && lamports(meta.PostBalances, balance.AccountIndex) == 0 {
+			rent -= int64(lamports(meta.PreBalances, balance.AccountIndex))
+		}
+	}
+	return rent
+}
+
+// ownerTokenBalance sums the owner's balances of the mint in UI units
+func ownerTokenBalance(balances []rpc.TokenBalance, owner solana.PublicKey, mint string) float64 {
+	var total float64
+	for _, balance := range balances {
+		if balance.Owner == nil || !balance.Owner.Equals(owner) || balance.Mint.String() != mint || balance.UiTokenAmount == nil {
+			continue
+		}
+		amount, err := strconv.ParseFloat(balance.UiTokenAmount.Amount, 64)
+		if err != nil {
+			continue
+		}
+		total += amount / math.Pow10(int(balance.UiTokenAmount.Decimals))
+	}
+	return total
+}
+
+// mintDecimals returns the decimals of SOL and the registered mints
+func mintDecimals(mint string) int {
+	if mint == SOLMint {
+		return 9
+	}
+	if token, ok := lookupTokenByMint(mint); ok {
+		return token.Decimals
+	}
+	return 6
+}
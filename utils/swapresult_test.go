@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestSwapAccountRent(t *testing.T) {
+	owner := solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+	other := solana.MustPublicKeyFromBase58("4k3Dyjzvzp8eMZWUXbBCjEvwSkkk59S5iCNLY3QrkX6R")
+	token := solana.MustPublicKeyFromBase58(testTokenMint)
+	wsol := solana.MustPublicKeyFromBase58(SOLMint)
+	balance := func(index uint16, owner solana.PublicKey, mint solana.PublicKey) rpc.TokenBalance {
+		return rpc.TokenBalance{AccountIndex: index, Owner: &owner, Mint: mint}
+	}
+
+	tests := []struct {
+		name string
+		meta rpc.TransactionMeta
+		want int64
+	}{
+		{
+			name: "buy creates the token account",
+			meta: rpc.TransactionMeta{
+				PreBalances:       []uint64{10000000, 0},
+				PostBalances:      []uint64{2955720, 2039280},
+				PostTokenBalances: []rpc.TokenBalance{balance(1, owner, token)},
+			},
+			want: 2039280,
+		},
+		{
+			name: "existing token account",
+			meta: rpc.TransactionMeta{
+				PreBalances:       []uint64{10000000, 2039280},
+				PostBalances:      []uint64{4995000, 2039280},
+				PreTokenBalances:  []rpc.TokenBalance{balance(1, owner, token)},
+				PostTokenBalances: []rpc.TokenBalance{balance(1, owner, token)},
+			},
+		},
+		{
+			name: "wrapped SOL account is created and closed",
+			meta: rpc.TransactionMeta{
+				PreBalances:       []uint64{10000000, 0},
+				PostBalances:      []uint64{14995000, 0},
+				PostTokenBalances: []rpc.TokenBalance{balance(1, owner, wsol)},
+			},
+		},
+		{
+			name: "token account of someone else",
+			meta: rpc.TransactionMeta{
+				PreBalances:       []uint64{10000000, 0},
+				PostBalances:      []uint64{7955720, 2039280},
+				PostTokenBalances: []rpc.TokenBalance{balance(1, other, token)},
+			},
+		},
+		{
+			name: "closed token account",
+			meta: rpc.TransactionMeta{
+				PreBalances:      []uint64{10000000, 2039280},
+				PostBalances:     []uint64{12034280, 0},
+				PreTokenBalances: []rpc.TokenBalance{balance(1, owner, token)},
+			},
+			want: -2039280,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := swapAccountRent(&tt.meta, owner); got != tt.want {
+				t.Errorf("swapAccountRent() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
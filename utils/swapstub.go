@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Price of tokens without an entry in StubSwapper.PricesSOL
+const defaultStubPriceSOL = 0.000001
+
+// StubSwapper is an offline swap backend. It quotes at fixed prices and
+// builds a memo-only transaction, so harvest logic can run without a live
+// aggregator and without spending anything.
+type StubSwapper struct {
+	// SOL price per token by mint
+	PricesSOL map[string]float64
+	// Price impact reported by every quote, as a fraction like Jupiter
+	PriceImpact float64
+}
+
+// NewStubSwapper creates a stub that prices every token at defaultStubPriceSOL
+func NewStubSwapper() *StubSwapper {
+	return &StubSwapper{PricesSOL: make(map[string]float64)}
+}
+
+func (s *StubSwapper) Name() string {
+	return "Stub"
+}
+
+func (s *StubSwapper) Offline() bool {
+	return true
+}
+
+func (s *StubSwapper) Quote(inputMint string, outputMint string, amount string, swapMode string, slippageBps int) (*QuoteResponse, error) {
+	rawAmount, err := strconv.ParseUint(amount, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount: %v", err)
+	}
+
+	inScale := math.Pow10(mintDecimals(inputMint))
+	outScale := math.Pow10(mintDecimals(outputMint))
+	// Output units per input unit, after price impact
+	rate := s.priceSOL(inputMint) / s.priceSOL(outputMint) * (1 - s.PriceImpact)
+	slippage := float64(slippageBps) / 10000
+
+	var inAmount, outAmount, threshold uint64
+	switch swapMode {
+	case SwapModeExactIn:
+		inAmount = rawAmount
+		outAmount = uint64(math.Floor(float64(rawAmount) / inScale * rate * outScale))
+		threshold = uint64(math.Floor(float64(outAmount) * (1 - slippage)))
+	case SwapModeExactOut:
+		outAmount = rawAmount
+		inAmount = uint64(math.Ceil(float64(rawAmount) / outScale / rate * inScale))
+		threshold = uint64(math.Ceil(float64(inAmount) * (1 + slippage)))
+	default:
+		return nil, fmt.Errorf("unknown swap mode: %s", swapMode)
+	}
+
+	inAmountText := strconv.FormatUint(inAmount, 10)
+	outAmountText := strconv.FormatUint(outAmount, 10)
+	return &QuoteResponse{
+		InputMint:            inputMint,
+		InAmount:             inAmountText,
+		OutputMint:           outputMint,
+		OutAmount:            outAmountText,
+		OtherAmountThreshold: strconv.FormatUint(threshold, 10),
+		SwapMode:             swapMode,
+		SlippageBps:          slippageBps,
+		PriceImpactPct:       strconv.FormatFloat(s.PriceImpact, 'f', -1, 64),
+		RoutePlan: []RoutePlanItem{{
+			SwapInfo: SwapInfo{
+				AmmKey:     solana.SystemProgramID.String(),
+				Label:      "Stub",
+				InputMint:  inputMint,
+				OutputMint: outputMint,
+				InAmount:   inAmountText,
+				OutAmount:  outAmountText,
+				FeeAmount:  "0",
+				FeeMint:    inputMint,
+			},
+			Percent: 100,
+		}},
+	}, nil
+}
+
+// SwapTransaction builds an unsigned transaction with only a memo describing the swap
+func (s *StubSwapper) SwapTransaction(quote *QuoteResponse, userPublicKey string) (*SwapResponse, error) {
+	owner, err := solana.PublicKeyFromBase58(userPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user public key: %v", err)
+	}
+
+	memo := fmt.Sprintf("stub swap %s %s -> %s %s", quote.InAmount, quote.InputMint, quote.OutAmount, quote.OutputMint)
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{
+			solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte(memo)),
+		},
+		solana.Hash{},
+		solana.TransactionPayer(owner),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build stub transaction: %v", err)
+	}
+
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode stub transaction: %v", err)
+	}
+	return &SwapResponse{SwapTransaction: encoded}, nil
+}
+
+func (s *StubSwapper) priceSOL(mint string) float64 {
+	if mint == SOLMint {
+		return 1
+	}
+	if price, ok := s.PricesSOL[mint]; ok && price > 0 {
+		return price
+	}
+	return defaultStubPriceSOL
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestStubSwapperQuote(t *testing.T) {
+	// 1024 tokens per SOL, exact in binary so the expected amounts are too
+	const tokenPriceSOL = 0.0009765625
+
+	tests := []struct {
+		name        string
+		inputMint   string
+		outputMint  string
+		amount      string
+		swapMode    string
+		slippageBps int
+		priceImpact float64
+		prices      map[string]float64
+
+		wantIn        string
+		wantOut       string
+		wantThreshold string
+		wantErr       bool
+	}{
+		{
+			name:       "sell 1 token, exact in",
+			inputMint:  testTokenMint,
+			outputMint: SOLMint,
+			amount:     "1000000",
+			swapMode:   SwapModeExactIn,
+			// 0.5%, the minimum output rounds down
+			slippageBps:   50,
+			prices:        map[string]float64{testTokenMint: tokenPriceSOL},
+			wantIn:        "1000000",
+			wantOut:       "976562",
+			wantThreshold: "971679",
+		},
+		{
+			name:          "sell 1 token with 1% price impact",
+			inputMint:     testTokenMint,
+			outputMint:    SOLMint,
+			amount:        "1000000",
+			swapMode:      SwapModeExactIn,
+			slippageBps:   50,
+			priceImpact:   0.01,
+			prices:        map[string]float64{testTokenMint: tokenPriceSOL},
+			wantIn:        "1000000",
+			wantOut:       "966796",
+			wantThreshold: "961962",
+		},
+		{
+			name:       "buy 1024 tokens, exact out",
+			inputMint:  SOLMint,
+			outputMint: testTokenMint,
+			amount:     "1024000000",
+			swapMode:   SwapModeExactOut,
+			// 1%, the maximum input rounds up
+			slippageBps:   100,
+			prices:        map[string]float64{testTokenMint: tokenPriceSOL},
+			wantIn:        "1000000000",
+			wantOut:       "1024000000",
+			wantThreshold: "1010000000",
+		},
+		{
+			name:          "buy 1024 tokens with 1% price impact",
+			inputMint:     SOLMint,
+			outputMint:    testTokenMint,
+			amount:        "1024000000",
+			swapMode:      SwapModeExactOut,
+			slippageBps:   100,
+			priceImpact:   0.01,
+			prices:        map[string]float64{testTokenMint: tokenPriceSOL},
+			wantIn:        "1010101011",
+			wantOut:       "1024000000",
+			wantThreshold: "1020202022",
+		},
+		{
+			name:          "token without a price uses the default",
+			inputMint:     SOLMint,
+			outputMint:    testTokenMint,
+			amount:        "1000000000",
+			swapMode:      SwapModeExactIn,
+			wantIn:        "1000000000",
+			wantOut:       "1000000000000",
+			wantThreshold: "1000000000000",
+		},
+		{
+			name:       "amount below one output unit",
+			inputMint:  testTokenMint,
+			outputMint: SOLMint,
+			amount:     "1",
+			swapMode:   SwapModeExactIn,
+			prices:     map[string]float64{testTokenMint: 0.0000001},
+			wantIn:     "1",
+			// 0.1 lamport
+			wantOut:       "0",
+			wantThreshold: "0",
+		},
+		{
+			name:       "invalid amount",
+			inputMint:  testTokenMint,
+			outputMint: SOLMint,
+			amount:     "1.5",
+			swapMode:   SwapModeExactIn,
+			wantErr:    true,
+		},
+		{
+			name:       "unknown swap mode",
+			inputMint:  testTokenMint,
+			outputMint: SOLMint,
+			amount:     "1000000",
+			swapMode:   "ExactBoth",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swapper := NewStubSwapper()
+			for mint, price := range tt.prices {
+				swapper.PricesSOL[mint] = price
+			}
+			swapper.PriceImpact = tt.priceImpact
+
+			quote, err := swapper.Quote(tt.inputMint, tt.outputMint, tt.amount, tt.swapMode, tt.slippageBps)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("quote %+v, want an error", quote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Quote: %v", err)
+			}
+
+			if quote.InAmount != tt.wantIn || quote.OutAmount != tt.wantOut || quote.OtherAmountThreshold != tt.wantThreshold {
+				t.Fatalf("in %s, out %s, threshold %s, want %s, %s, %s",
+					quote.InAmount, quote.OutAmount, quote.OtherAmountThreshold, tt.wantIn, tt.wantOut, tt.wantThreshold)
+			}
+			if quote.SwapMode != tt.swapMode || quote.SlippageBps != tt.slippageBps {
+				t.Errorf("mode %s, slippage %d", quote.SwapMode, quote.SlippageBps)
+			}
+			if impact, _ := strconv.ParseFloat(quote.PriceImpactPct, 64); impact != tt.priceImpact {
+				t.Errorf("price impact %s, want %v", quote.PriceImpactPct, tt.priceImpact)
+			}
+			if len(quote.RoutePlan) != 1 || quote.RoutePlan[0].SwapInfo.InAmount != quote.InAmount || quote.RoutePlan[0].SwapInfo.OutAmount != quote.OutAmount {
+				t.Errorf("route plan %+v doesn't match the quote", quote.RoutePlan)
+			}
+
+			// The stub quote has to pass the same bounds a real one does
+			maxIn, err := quote.maxInAmount()
+			if err != nil {
+				t.Fatal(err)
+			}
+			minOut, err := quote.minOutAmount()
+			if err != nil {
+				t.Fatal(err)
+			}
+			in, _ := strconv.ParseInt(quote.InAmount, 10, 64)
+			out, _ := strconv.ParseInt(quote.OutAmount, 10, 64)
+			if maxIn < in || minOut > out {
+				t.Errorf("bounds in <= %d, out >= %d don't hold for %d -> %d", maxIn, minOut, in, out)
+			}
+		})
+	}
+}
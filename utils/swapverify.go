@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	JupiterProgramID       = "JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4"
+	ComputeBudgetProgramID = "ComputeBudget111111111111111111111111111111"
+
+	// Upper bound for the priority fee of a swap (0.005 SOL)
+	maxSwapPriorityFeeLamports = 5_000_000
+	// Rent a swap may put into new token accounts: the input and the
+	// output account of 165 bytes each
+	maxSwapRentLamports = 2 * 2_039_280
+	// Base fee of the single signature of a swap
+	maxSwapBaseFeeLamports = 5_000
+)
+
+var (
+	jupiterProgramKey       = solana.MustPublicKeyFromBase58(JupiterProgramID)
+	computeBudgetProgramKey = solana.MustPublicKeyFromBase58(ComputeBudgetProgramID)
+
+	// Programs a swap transaction may call at the top level
+	allowedSwapPrograms = map[solana.PublicKey]string{
+		jupiterProgramKey:                         "Jupiter",
+		solana.TokenProgramID:                     "Token",
+		solana.SPLAssociatedTokenAccountProgramID: "Associated Token Account",
+		computeBudgetProgramKey:                   "Compute Budget",
+		solana.SystemProgramID:                    "System",
+		solana.MemoProgramID:                      "Memo",
+	}
+)
+
+// verifySwapTransaction checks an aggregator swap transaction against our
+// signing policy: we pay the fee, only known programs are called and the
+// non-Jupiter instructions can only move funds between our own accounts
+func verifySwapTransaction(client *rpc.Client, tx *solana.Transaction, owner solana.PublicKey, quote *QuoteResponse) error {
+	message := &tx.Message
+
+	if len(message.AccountKeys) == 0 {
+		return fmt.Errorf("swap transaction has no accounts")
+	}
+	if !message.AccountKeys[0].Equals(owner) {
+		return fmt.Errorf("swap transaction fee payer %s is not our wallet", message.AccountKeys[0])
+	}
+	if message.Header.NumRequiredSignatures != 1 {
+		return fmt.Errorf("swap transaction requires %d signatures, expected 1", message.Header.NumRequiredSignatures)
+	}
+
+	keys, err := resolveMessageKeys(client, message)
+	if err != nil {
+		return fmt.Errorf("failed to resolve swap transaction accounts: %v", err)
+	}
+
+	maxInAmount, err := quote.maxInAmount()
+	if err != nil {
+		return fmt.Errorf("invalid quote input amount: %v", err)
+	}
+
+	wrappedSOLAccount, _, err := solana.FindAssociatedTokenAddress(owner, solana.SolMint)
+	if err != nil {
+		return fmt.Errorf("failed to find wrapped SOL account: %v", err)
+	}
+
+	var computeUnitLimit uint64 = 200_000
+	var computeUnitPrice uint64
+
+	for i, instruction := range message.Instructions {
+		if int(instruction.ProgramIDIndex) >= len(keys) {
+			return fmt.Errorf("instruction %d: program index out of range", i)
+		}
+		programID := keys[instruction.ProgramIDIndex]
+		if _, ok := allowedSwapPrograms[programID]; !ok {
+			return fmt.Errorf("instruction %d: program %s is not allowed", i, programID)
+		}
+
+		accounts := make([]solana.PublicKey, 0, len(instruction.Accounts))
+		for _, index := range instruction.Accounts {
+			if int(index) >= len(keys) {
+				return fmt.Errorf("instruction %d: account index out of range", i)
+			}
+			accounts = append(accounts, keys[index])
+		}
+		data := []byte(instruction.Data)
+
+		switch programID {
+		case computeBudgetProgramKey:
+			if len(data) == 5 && data[0] == 2 {
+				computeUnitLimit = uint64(binary.LittleEndian.Uint32(data[1:]))
+			} else if len(data) == 9 && data[0] == 3 {
+				computeUnitPrice = binary.LittleEndian.Uint64(data[1:])
+			}
+
+		case solana.SystemProgramID:
+			// Only a transfer into our wrapped SOL account is expected
+			if len(data) != 12 || binary.LittleEndian.Uint32(data) != 2 || len(accounts) < 2 {
+				return fmt.Errorf("instruction %d: unexpected System instruction", i)
+			}
+			if !accounts[0].Equals(owner) || !accounts[1].Equals(wrappedSOLAccount) {
+				return fmt.Errorf("instruction %d: SOL transfer to %s is not allowed", i, accounts[1])
+			}
+			lamports := binary.LittleEndian.Uint64(data[4:])
+			if quote.InputMint != SOLMint || lamports > uint64(maxInAmount) {
+				return fmt.Errorf("instruction %d: SOL transfer of %d lamports exceeds the quote", i, lamports)
+			}
+
+		case solana.TokenProgramID:
+			// Only wrapped SOL handling: SyncNative and CloseAccount back to us
+			if len(data) == 0 {
+				return fmt.Errorf("instruction %d: empty Token instruction", i)
+			}
+			switch data[0] {
+			case 17: // SyncNative
+			case 9: // CloseAccount
+				if len(accounts) < 3 || !accounts[1].Equals(owner) || !accounts[2].Equals(owner) {
+					return fmt.Errorf("instruction %d: token account close does not return funds to our wallet", i)
+				}
+			default:
+				return fmt.Errorf("instruction %d: Token instruction %d is not allowed", i, data[0])
+			}
+
+		case solana.SPLAssociatedTokenAccountProgramID:
+			if len(accounts) < 3 || !accounts[0].Equals(owner) || !accounts[2].Equals(owner) {
+				return fmt.Errorf("instruction %d: token account is not created for our wallet", i)
+			}
+		}
+	}
+
+	if err := checkPriorityFee(computeUnitLimit, computeUnitPrice); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkPriorityFee checks the priority fee of a compute unit limit and a
+// price in micro-lamports per unit, whose product may not fit in 64 bits
+func checkPriorityFee(computeUnitLimit uint64, computeUnitPrice uint64) error {
+	hi, lo := bits.Mul64(computeUnitLimit, computeUnitPrice)
+	if hi >= 1_000_000 {
+		return fmt.Errorf("priority fee of %d units at %d micro-lamports exceeds the limit of %d lamports", computeUnitLimit, computeUnitPrice, maxSwapPriorityFeeLamports)
+	}
+	priorityFee, _ := bits.Div64(hi, lo, 1_000_000)
+	if priorityFee > maxSwapPriorityFeeLamports {
+		return fmt.Errorf("priority fee of %d lamports exceeds the limit of %d", priorityFee, maxSwapPriorityFeeLamports)
+	}
+	return nil
+}
+
+// verifySwapFlows checks the simulated balance changes against the quote:
+// we spend no more than the maximum input and receive at least the minimum
+// output. The SOL balance change also pays the transaction fee and the rent
+// of new token accounts, which are taken out before comparing.
+func verifySwapFlows(preview *SimulationPreview, quote *QuoteResponse) error {
+	if preview.Failed() {
+		return fmt.Errorf("swap simulation failed: %s", preview.Err)
+	}
+
+	// Every balance must be known
+	for _, change := range preview.TokenChanges {
+		if change.Unknown {
+			return fmt.Errorf("swap changes an unknown amount of %s", change.Symbol)
+		}
+	}
+
+	if preview.RentLamports > maxSwapRentLamports {
+		return fmt.Errorf("swap puts %d lamports of rent into new token accounts, the limit is %d", preview.RentLamports, maxSwapRentLamports)
+	}
+	if preview.FeeLamports > maxSwapPriorityFeeLamports+maxSwapBaseFeeLamports {
+		return fmt.Errorf("swap fee of %d lamports exceeds the limit of %d", preview.FeeLamports, maxSwapPriorityFeeLamports+maxSwapBaseFeeLamports)
+	}
+	// SOL the swap itself moved, without fee and rent
+	swapLamports := preview.LamportsChange + int64(preview.FeeLamports) + preview.RentLamports
+
+	inAmount, err := quote.maxInAmount()
+	if err != nil {
+		return fmt.Errorf("invalid quote input amount: %v", err)
+	}
+	minOutAmount, err := quote.minOutAmount()
+	if err != nil {
+		return fmt.Errorf("invalid quote output amount: %v", err)
+	}
+
+	// Input side
+	if quote.InputMint == SOLMint {
+		if spent := -swapLamports; spent > inAmount {
+			return fmt.Errorf("swap spends %d lamports, quote allows %d", spent, inAmount)
+		}
+	} else {
+		change, _ := preview.TokenChange(quote.InputMint)
+		if spent := -change.RawChange; spent > inAmount {
+			return fmt.Errorf("swap spends %d units of %s, quote allows %d", spent, getTokenSymbol(quote.InputMint), inAmount)
+		}
+	}
+
+	// Output side
+	if quote.OutputMint == SOLMint {
+		if received := swapLamports; received < minOutAmount {
+			return fmt.Errorf("swap returns %d lamports, quote guarantees %d", received, minOutAmount)
+		}
+	} else {
+		change, _ := preview.TokenChange(quote.OutputMint)
+		if change.RawChange < minOutAmount {
+			return fmt.Errorf("swap returns %d units of %s, quote guarantees %d", change.RawChange, getTokenSymbol(quote.OutputMint), minOutAmount)
+		}
+	}
+
+	// No other token account of ours may lose tokens, registered or not
+	for _, change := range preview.TokenChanges {
+		if change.Mint != quote.InputMint && change.RawChange < 0 {
+			return fmt.Errorf("swap unexpectedly spends %f %s from %s", -change.Change, change.Symbol, change.Account)
+		}
+	}
+
+	return nil
+}
+
+// resolveMessageKeys returns the static keys of the message followed by the
+// keys loaded from its address lookup tables
+func resolveMessageKeys(client *rpc.Client, message *solana.Message) (solana.PublicKeySlice, error) {
+	if message.NumLookups() == 0 {
+		return message.AccountKeys, nil
+	}
+
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice)
+	for _, lookup := range message.GetAddressTableLookups() {
+		state, err := addresslookuptable.GetAddressLookupTable(context.TODO(), client, lookup.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get address lookup table %s: %v", lookup.AccountKey, err)
+		}
+		tables[lookup.AccountKey] = state.Addresses
+	}
+
+	if err := message.SetAddressTables(tables); err != nil {
+		return nil, err
+	}
+	return message.GetAllKeys()
+}
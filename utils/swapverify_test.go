@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const testTokenMint = "2Sm5Y7CEfwGFMjkY6ctmAkX6eGNV5pyStNk6WkJ6Ajqw"
+
+func TestVerifySwapFlows(t *testing.T) {
+	// 0.005 SOL for 1000 token units, at least 0.004 SOL after slippage
+	sell := &QuoteResponse{
+		InputMint:            testTokenMint,
+		OutputMint:           SOLMint,
+		InAmount:             "1000",
+		OutAmount:            "5000000",
+		OtherAmountThreshold: "4000000",
+		SwapMode:             SwapModeExactIn,
+	}
+	// 1000 token units for 0.005 SOL, at least 900 after slippage
+	buy := &QuoteResponse{
+		InputMint:            SOLMint,
+		OutputMint:           testTokenMint,
+		InAmount:             "5000000",
+		OutAmount:            "1000",
+		OtherAmountThreshold: "900",
+		SwapMode:             SwapModeExactIn,
+	}
+	spendTokens := TokenBalanceChange{Mint: testTokenMint, RawChange: -1000}
+
+	tests := []struct {
+		name    string
+		quote   *QuoteResponse
+		preview SimulationPreview
+		// Part of the error, empty if the swap passes
+		wantErr string
+	}{
+		{
+			name:  "sell receives the minimum",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000 - 5000,
+				FeeLamports:    5000,
+				TokenChanges:   []TokenBalanceChange{spendTokens},
+			},
+		},
+		{
+			name:  "sell receives nothing",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: -5000,
+				FeeLamports:    5000,
+				TokenChanges:   []TokenBalanceChange{spendTokens},
+			},
+			wantErr: "returns 0 lamports",
+		},
+		{
+			name:  "sell receives one lamport short",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000 - 5000 - 1,
+				FeeLamports:    5000,
+				TokenChanges:   []TokenBalanceChange{spendTokens},
+			},
+			wantErr: "quote guarantees 4000000",
+		},
+		{
+			name:  "sell pays the rent of a new token account",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000 - 5000 - 2039280,
+				FeeLamports:    5000,
+				RentLamports:   2039280,
+				TokenChanges:   []TokenBalanceChange{spendTokens},
+			},
+		},
+		{
+			name:  "sell spends more tokens than quoted",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000,
+				TokenChanges:   []TokenBalanceChange{{Mint: testTokenMint, RawChange: -1001}},
+			},
+			wantErr: "spends 1001 units",
+		},
+		{
+			name:  "buy spends the input, fee and rent",
+			quote: buy,
+			preview: SimulationPreview{
+				LamportsChange: -5000000 - 5000 - 2039280,
+				FeeLamports:    5000,
+				RentLamports:   2039280,
+				TokenChanges:   []TokenBalanceChange{{Mint: testTokenMint, RawChange: 950}},
+			},
+		},
+		{
+			name:  "buy spends more SOL than quoted",
+			quote: buy,
+			preview: SimulationPreview{
+				LamportsChange: -5000000 - 5000 - 1,
+				FeeLamports:    5000,
+				TokenChanges:   []TokenBalanceChange{{Mint: testTokenMint, RawChange: 950}},
+			},
+			wantErr: "spends 5000001 lamports",
+		},
+		{
+			name:  "buy receives too few tokens",
+			quote: buy,
+			preview: SimulationPreview{
+				LamportsChange: -5000000,
+				TokenChanges:   []TokenBalanceChange{{Mint: testTokenMint, RawChange: 899}},
+			},
+			wantErr: "returns 899 units",
+		},
+		{
+			name:  "too much rent",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000,
+				RentLamports:   maxSwapRentLamports + 1,
+				TokenChanges:   []TokenBalanceChange{spendTokens},
+			},
+			wantErr: "rent",
+		},
+		{
+			name:  "unknown balance",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000,
+				TokenChanges:   []TokenBalanceChange{{Mint: testTokenMint, Symbol: "XEN", Unknown: true}},
+			},
+			wantErr: "unknown amount of XEN",
+		},
+		{
+			name:  "other token leaves the wallet",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000,
+				TokenChanges: []TokenBalanceChange{
+					spendTokens,
+					{Mint: "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB", Symbol: "USDT", Change: -1, RawChange: -1000000},
+				},
+			},
+			wantErr: "unexpectedly spends",
+		},
+		{
+			name:  "sell spends the input from two accounts",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000,
+				TokenChanges: []TokenBalanceChange{
+					{Account: "ata", Mint: testTokenMint, RawChange: -600},
+					{Account: "other", Mint: testTokenMint, RawChange: -400},
+				},
+			},
+		},
+		{
+			name:  "sell spends too much over two accounts",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000,
+				TokenChanges: []TokenBalanceChange{
+					{Account: "ata", Mint: testTokenMint, RawChange: -1000},
+					{Account: "other", Mint: testTokenMint, RawChange: -1},
+				},
+			},
+			wantErr: "spends 1001 units",
+		},
+		{
+			name:  "unregistered token leaves the wallet",
+			quote: sell,
+			preview: SimulationPreview{
+				LamportsChange: 4000000,
+				TokenChanges: []TokenBalanceChange{
+					spendTokens,
+					{Account: "stray", Mint: "4k3Dyjzvzp8eMZWUXbBCjEvwSkkk59S5iCNLY3QrkX6R", Symbol: "4k3Dyjzvzp8eMZWUXbBCjEvwSkkk59S5iCNLY3QrkX6R", Change: -0.5, RawChange: -500000},
+				},
+			},
+			wantErr: "unexpectedly spends 0.500000 4k3Dyjzvzp8eMZWUXbBCjEvwSkkk59S5iCNLY3QrkX6R from stray",
+		},
+		{
+			name:    "failed simulation",
+			quote:   sell,
+			preview: SimulationPreview{Err: "custom program error: 0x1771"},
+			wantErr: "simulation failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySwapFlows(&tt.preview, tt.quote)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPriorityFee(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   uint64
+		price   uint64
+		wantErr bool
+	}{
+		{"no priority fee", 200_000, 0, false},
+		{"at the limit", 1_000_000, 5_000_000, false},
+		{"one lamport over", 1_000_000, 5_000_001, true},
+		{"product overflows 64 bits", 1 << 32, math.MaxUint64 / (1 << 31), true},
+		{"huge price", 1, math.MaxUint64, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPriorityFee(tt.limit, tt.price)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const tokenRegistryFileName = "tokens.json"
+
+// TokenInfo is one entry of the token registry
+type TokenInfo struct {
+	Mint     string `json:"mint"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// defaultTokens seeds the registry file on first start
+var defaultTokens = []TokenInfo{
+	{Mint: "6f8deE148nynnSiWshA9vLydEbJGpDeKh5G4PRgjmzG7", Symbol: SolXEN, Decimals: 6, Enabled: true},
+	{Mint: "EEqrab5tdnVdZv7a4AUAvGehDAtM8gWd7szwfyYbmGkM", Symbol: OGSolXEN, Decimals: 6, Enabled: false},
+	{Mint: "7UN8WkBumTUCofVPXCPjNWQ6msQhzrg9tFQRP48Nmw5V", Symbol: xencat, Decimals: 6, Enabled: true},
+	{Mint: "5px3a5LWR6CmiYX3ktpNnGYiEypfDdemRd74GDYbsJ2H", Symbol: PV, Decimals: 6, Enabled: true},
+	{Mint: "oreoU2P8bN6jkk3jbaiVxYnG1dCXcYxwhwyK9jSybcp", Symbol: ORE, Decimals: 11, Enabled: true},
+}
+
+var (
+	tokenRegistry         []TokenInfo
+	tokenRegistryMutex    sync.RWMutex
+	tokenRegistryLoadOnce sync.Once
+)
+
+// loadTokenRegistry reads the registry file once, creating it from the
+// defaults if it doesn't exist yet
+func loadTokenRegistry() {
+	tokenRegistryLoadOnce.Do(func() {
+		tokens, err := ReadTokenRegistryFile()
+		if err != nil {
+			if !os.IsNotExist(err) {
+				LogToFile(fmt.Sprintf("Failed to read token registry, using defaults: %v", err))
+			}
+			tokens = append([]TokenInfo(nil), defaultTokens...)
+			if os.IsNotExist(err) {
+				if err := WriteTokenRegistryFile(tokens); err != nil {
+					LogToFile("Failed to create default token registry: " + err.Error())
+				}
+			}
+		}
+
+		tokenRegistryMutex.Lock()
+		tokenRegistry = tokens
+		tokenRegistryMutex.Unlock()
+	})
+}
+
+func ReadTokenRegistryFile() ([]TokenInfo, error) {
+	file, err := os.ReadFile(filepath.Join(GetExecutablePath(), tokenRegistryFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []TokenInfo
+	if err := json.Unmarshal(file, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func WriteTokenRegistryFile(tokens []TokenInfo) error {
+	file, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(GetExecutablePath(), tokenRegistryFileName), file, 0644)
+}
+
+// GetTokens returns all registered tokens
+func GetTokens() []TokenInfo {
+	loadTokenRegistry()
+
+	tokenRegistryMutex.RLock()
+	defer tokenRegistryMutex.RUnlock()
+	return append([]TokenInfo(nil), tokenRegistry...)
+}
+
+// GetEnabledTokens returns the registered tokens that are enabled
+func GetEnabledTokens() []TokenInfo {
+	var tokens []TokenInfo
+	for _, token := range GetTokens() {
+		if token.Enabled {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// GetEnabledTokenSymbols returns the symbols of the enabled tokens in registry order
+func GetEnabledTokenSymbols() []string {
+	var symbols []string
+	for _, token := range GetEnabledTokens() {
+		symbols = append(symbols, token.Symbol)
+	}
+	return symbols
+}
+
+// lookupToken returns the enabled token with the given symbol
+func lookupToken(symbol string) (TokenInfo, bool) {
+	for _, token := range GetEnabledTokens() {
+		if token.Symbol == symbol {
+			return token, true
+		}
+	}
+	return TokenInfo{}, false
+}
+
+// lookupTokenByMint returns the registered token with the given mint, enabled or not
+func lookupTokenByMint(mint string) (TokenInfo, bool) {
+	for _, token := range GetTokens() {
+		if token.Mint == mint {
+			return token, true
+		}
+	}
+	return TokenInfo{}, false
+}
+
+// AddToken validates an SPL mint on-chain and adds it to the registry as enabled
+func AddToken(mint string, symbol string) (TokenInfo, error) {
+	mint = strings.TrimSpace(mint)
+	symbol = strings.TrimSpace(symbol)
+	if symbol == "" {
+		return TokenInfo{}, fmt.Errorf("token symbol is required")
+	}
+	if strings.EqualFold(symbol, SOL) {
+		return TokenInfo{}, fmt.Errorf("symbol %s is reserved", SOL)
+	}
+
+	for _, token := range GetTokens() {
+		if token.Mint == mint {
+			return TokenInfo{}, fmt.Errorf("mint %s is already registered as %s", mint, token.Symbol)
+		}
+		if strings.EqualFold(token.Symbol, symbol) {
+			return TokenInfo{}, fmt.Errorf("symbol %s is already registered", token.Symbol)
+		}
+	}
+
+	decimals, err := validateTokenMint(mint)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+
+	token := TokenInfo{Mint: mint, Symbol: symbol, Decimals: decimals, Enabled: true}
+
+	tokenRegistryMutex.Lock()
+	defer tokenRegistryMutex.Unlock()
+
+	tokens := append(append([]TokenInfo(nil), tokenRegistry...), token)
+	if err := WriteTokenRegistryFile(tokens); err != nil {
+		return TokenInfo{}, fmt.Errorf("failed to save token registry: %v", err)
+	}
+	tokenRegistry = tokens
+
+	LogToFile(fmt.Sprintf("Added token %s (%s, %d decimals)", symbol, mint, decimals))
+	return token, nil
+}
+
+// SetTokenEnabled enables or disables a registered token
+func SetTokenEnabled(symbol string, enabled bool) error {
+	loadTokenRegistry()
+
+	tokenRegistryMutex.Lock()
+	defer tokenRegistryMutex.Unlock()
+
+	tokens := append([]TokenInfo(nil), tokenRegistry...)
+	for i := range tokens {
+		if tokens[i].Symbol == symbol {
+			tokens[i].Enabled = enabled
+			if err := WriteTokenRegistryFile(tokens); err != nil {
+				return fmt.Errorf("failed to save token registry: %v", err)
+			}
+			tokenRegistry = tokens
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown token: %s", symbol)
+}
+
+// validateTokenMint checks that the address is an initialized SPL Token mint
+// and returns its decimals. Token-2022 mints are refused with their own error.
+func validateTokenMint(mint string) (int, error) {
+	mintKey, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mint address: %v", err)
+	}
+
+	client := rpc.New(SolanaRPCURL)
+	account, err := client.GetAccountInfoWithOpts(context.TODO(), mintKey, &rpc.GetAccountInfoOpts{
+		Encoding:   solana.EncodingJSONParsed,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mint account: %v", err)
+	}
+	if account == nil || account.Value == nil {
+		return 0, fmt.Errorf("mint account %s not found", mint)
+	}
+	// Token-2022 mints are refused: their token accounts are derived with the
+	// Token-2022 program, while the wallet, burns, simulations and the swap
+	// checks all work with SPL Token accounts
+	if account.Value.Owner.Equals(solana.Token2022ProgramID) {
+		return 0, fmt.Errorf("mint %s is a Token-2022 token, only SPL Token mints are supported", mint)
+	}
+	if !account.Value.Owner.Equals(solana.TokenProgramID) {
+		return 0, fmt.Errorf("account %s is not owned by the SPL Token program", mint)
+	}
+
+	if account.Value.Data == nil {
+		return 0, fmt.Errorf("account %s has no data", mint)
+	}
+
+	var parsed struct {
+		Parsed struct {
+			Type string `json:"type"`
+			Info struct {
+				Decimals      int  `json:"decimals"`
+				IsInitialized bool `json:"isInitialized"`
+			} `json:"info"`
+		} `json:"parsed"`
+	}
+	if err := json.Unmarshal(account.Value.Data.GetRawJSON(), &parsed); err != nil {
+		return 0, fmt.Errorf("failed to parse mint account: %v", err)
+	}
+	if parsed.Parsed.Type != "mint" || !parsed.Parsed.Info.IsInitialized {
+		return 0, fmt.Errorf("account %s is not an initialized token mint", mint)
+	}
+
+	return parsed.Parsed.Info.Decimals, nil
+}
@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+const twapOrderFileName = "twapOrder.json"
+
+// A TWAP order gives up on its remaining slices this long after its window ended
+const twapGracePeriod = 1 * time.Hour
+
+// How often a slice in flight is looked up while the RPC node can't tell
+const twapResolveInterval = time.Minute
+
+// TWAPOrder splits a harvest into slices that are swapped evenly over a
+// time window. Its progress is saved after every slice, so a restart resumes it.
+type TWAPOrder struct {
+	Token             string    `json:"token"`
+	TotalSOL          float64   `json:"totalSOL"`
+	Slices            int       `json:"slices"`
+	Window            string    `json:"window"`
+	SlippageBps       int       `json:"slippageBps"`
+	MaxPriceImpactPct float64   `json:"maxPriceImpactPct"`
+	StartedAt         time.Time `json:"startedAt"`
+	NextSliceAt       time.Time `json:"nextSliceAt"`
+	SlicesDone        int       `json:"slicesDone"`
+	// SOL swapped for the token, the slices are sized from it
+	SwappedSOL float64 `json:"swappedSOL"`
+	// SOL that left the wallet, the swapped SOL plus fees and token account rent
+	SpentSOL       float64  `json:"spentSOL"`
+	ReceivedTokens float64  `json:"receivedTokens"`
+	Signatures     []string `json:"signatures"`
+	// Set while a slice is being swapped. Found set on resume, the slice is
+	// looked up on-chain by the signatures of its attempts, which are saved
+	// before each is sent, rather than bought twice.
+	SliceInFlight   bool      `json:"sliceInFlight"`
+	SliceSignatures []string  `json:"sliceSignatures"`
+	SliceSentAt     time.Time `json:"sliceSentAt"`
+}
+
+var twapMutex sync.Mutex
+
+// NewTWAPOrder creates an order and saves it, so it survives a restart
+func NewTWAPOrder(token string, totalSOL float64, slices int, window time.Duration, slippageBps int, maxPriceImpactPct float64) (*TWAPOrder, error) {
+	if slices < 1 {
+		return nil, fmt.Errorf("a TWAP order needs at least 1 slice")
+	}
+	if _, ok := lookupToken(token); !ok {
+		return nil, fmt.Errorf("unknown token: %s", token)
+	}
+
+	now := time.Now()
+	order := &TWAPOrder{
+		Token:             token,
+		TotalSOL:          totalSOL,
+		Slices:            slices,
+		Window:            window.String(),
+		SlippageBps:       slippageBps,
+		MaxPriceImpactPct: maxPriceImpactPct,
+		StartedAt:         now,
+		NextSliceAt:       now,
+	}
+	if err := order.save(); err != nil {
+		return nil, fmt.Errorf("failed to save TWAP order: %v", err)
+	}
+	return order, nil
+}
+
+// LoadTWAPOrder returns the saved unfinished order, or nil if there is none
+func LoadTWAPOrder() (*TWAPOrder, error) {
+	file, err := os.ReadFile(filepath.Join(GetExecutablePath(), twapOrderFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var order TWAPOrder
+	if err := json.Unmarshal(file, &order); err != nil {
+		return nil, err
+	}
+	// Orders saved before the swapped SOL was tracked only know the spent SOL
+	if order.SwappedSOL == 0 && order.SpentSOL > 0 {
+		order.SwappedSOL = order.SpentSOL
+	}
+	return &order, nil
+}
+
+// TWAPOrderActive reports whether an unfinished order exists. An order file
+// that can't be read counts as an order, so no second one is started over it.
+func TWAPOrderActive() bool {
+	order, err := LoadTWAPOrder()
+	if err != nil {
+		LogToFile(fmt.Sprintf("Failed to read TWAP order, treating it as active: %v", err))
+		return true
+	}
+	return order != nil
+}
+
+// String formats the progress of the order for the harvest log
+func (o *TWAPOrder) String() string {
+	return fmt.Sprintf("TWAP %s: %d/%d slices, %s/%s SOL swapped (%s SOL spent with fees and rent), %s %s received",
+		o.Token, o.SlicesDone, o.Slices,
+		strconv.FormatFloat(o.SwappedSOL, 'f', -1, 64), strconv.FormatFloat(o.TotalSOL, 'f', -1, 64),
+		strconv.FormatFloat(o.SpentSOL, 'f', -1, 64),
+		strconv.FormatFloat(o.ReceivedTokens, 'f', -1, 64), o.Token)
+}
+
+// Run swaps the remaining slices of the order, waiting for each slice's turn.
+// Slices that fail or exceed the price impact limit are retried at the next
+// slice interval until the window plus a grace period has passed.
+func (o *TWAPOrder) Run(logView *tview.TextView) error {
+	// Only one order runs at a time
+	if !twapMutex.TryLock() {
+		return errors.New("another TWAP order is running")
+	}
+	defer twapMutex.Unlock()
+
+	window, err := time.ParseDuration(o.Window)
+	if err != nil {
+		return fmt.Errorf("invalid TWAP window %q: %v", o.Window, err)
+	}
+	sliceInterval := window / time.Duration(o.Slices)
+	deadline := o.StartedAt.Add(window + twapGracePeriod)
+
+	if o.SliceInFlight {
+		LogMessage(logView, fmt.Sprintf("TWAP %s: slice %d was interrupted, looking it up on-chain", o.Token, o.SlicesDone+1))
+		if err := o.resolveSliceInFlight(logView); err != nil {
+			return err
+		}
+	}
+
+	for o.SlicesDone < o.Slices {
+		if wait := time.Until(o.NextSliceAt); wait > 0 {
+			time.Sleep(wait)
+		}
+		if time.Now().After(deadline) {
+			LogMessage(logView, fmt.Sprintf("%s, window ended, remaining slices abandoned", o))
+			break
+		}
+
+		sliceSOL, ok := o.nextSliceSOL()
+		if !ok {
+			LogMessage(logView, fmt.Sprintf("%s, nothing left to swap", o))
+			break
+		}
+		sliceAmount := strconv.FormatFloat(sliceSOL, 'f', 9, 64)
+		o.NextSliceAt = time.Now().Add(sliceInterval)
+
+		// Check the quote first, a slice above the impact limit waits for the next interval
+		quote, err := GetSwapQuote(SOL, o.Token, sliceAmount, SwapModeExactIn, o.SlippageBps)
+		if err == nil {
+			err = CheckPriceImpact(quote, o.MaxPriceImpactPct)
+		}
+		if err != nil {
+			LogMessage(logView, fmt.Sprintf("TWAP %s slice %d/%d postponed: %v", o.Token, o.SlicesDone+1, o.Slices, err))
+			if err := o.save(); err != nil {
+				LogToFile(fmt.Sprintf("Failed to save TWAP order: %v", err))
+			}
+			continue
+		}
+
+		o.SliceInFlight = true
+		o.SliceSignatures = nil
+		if err := o.save(); err != nil {
+			return fmt.Errorf("failed to save TWAP order: %v", err)
+		}
+
+		result, err := ExchangeSolForToken(sliceAmount, o.Token, SwapOptions{
+			SlippageBps:       o.SlippageBps,
+			MaxPriceImpactPct: o.MaxPriceImpactPct,
+			Quote:             quote,
+			BeforeSend: func(signature string) error {
+				o.SliceSignatures = append(o.SliceSignatures, signature)
+				o.SliceSentAt = time.Now()
+				return o.save()
+			},
+		})
+		if errors.Is(err, ErrSwapOutcomeUnknown) {
+			// The slice may still execute, it is looked up once it can't anymore
+			LogMessage(logView, fmt.Sprintf("TWAP %s slice %d/%d: %v", o.Token, o.SlicesDone+1, o.Slices, err))
+			if err := o.resolveSliceInFlight(logView); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			LogMessage(logView, fmt.Sprintf("TWAP %s slice %d/%d failed: %v", o.Token, o.SlicesDone+1, o.Slices, err))
+			o.clearSliceInFlight()
+		} else {
+			o.sliceDone(result)
+			LogMessage(logView, fmt.Sprintf("%s successfully", result))
+			LogMessage(logView, o.String())
+		}
+		if err := o.save(); err != nil {
+			LogToFile(fmt.Sprintf("Failed to save TWAP order: %v", err))
+		}
+	}
+
+	if o.SlicesDone == o.Slices {
+		LogMessage(logView, fmt.Sprintf("%s, order complete", o))
+	}
+	return o.remove()
+}
+
+// nextSliceSOL spreads the SOL not swapped yet over the remaining slices, so
+// retried slices keep the total. Fees and rent don't count against it. It
+// returns false once less than a lamport is left.
+func (o *TWAPOrder) nextSliceSOL() (float64, bool) {
+	remaining := o.TotalSOL - o.SwappedSOL
+	if remaining < 1e-9 || o.SlicesDone >= o.Slices {
+		return 0, false
+	}
+	sliceSOL := remaining / float64(o.Slices-o.SlicesDone)
+	return math.Max(1e-9, math.Min(sliceSOL, remaining)), true
+}
+
+// resolveSliceInFlight finds out whether the slice in flight landed, once
+// none of its attempts can land anymore. A landed slice is counted with its
+// on-chain amounts, otherwise it is swapped again. While the RPC node can't
+// tell, the lookup is retried, the slice must not be bought twice.
+func (o *TWAPOrder) resolveSliceInFlight(logView *tview.TextView) error {
+	// A crash before the first attempt was sent
+	if len(o.SliceSignatures) == 0 {
+		LogMessage(logView, fmt.Sprintf("TWAP %s: slice %d was not sent, it will be swapped again", o.Token, o.SlicesDone+1))
+		o.clearSliceInFlight()
+		return o.save()
+	}
+
+	if wait := time.Until(o.SliceSentAt.Add(blockhashLifetime)); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	for {
+		result, err := ResolveSwap(SOL, o.Token, o.SliceSignatures)
+		if err == nil {
+			if result == nil {
+				LogMessage(logView, fmt.Sprintf("TWAP %s: slice %d did not land, it will be swapped again", o.Token, o.SlicesDone+1))
+				o.clearSliceInFlight()
+			} else {
+				o.sliceDone(result)
+				LogMessage(logView, fmt.Sprintf("TWAP %s: slice %d landed, %s", o.Token, o.SlicesDone, result))
+				LogMessage(logView, o.String())
+			}
+			return o.save()
+		}
+
+		LogMessage(logView, fmt.Sprintf("TWAP %s: slice %d can't be looked up, retrying: %v", o.Token, o.SlicesDone+1, err))
+		time.Sleep(twapResolveInterval)
+	}
+}
+
+// sliceDone counts the slice in flight with the amounts of its swap
+func (o *TWAPOrder) sliceDone(result *SwapResult) {
+	o.SlicesDone++
+	o.SwappedSOL += result.AmountIn
+	o.SpentSOL += result.AmountIn + result.FeeSOL + result.RentSOL
+	o.ReceivedTokens += result.AmountOut
+	o.Signatures = append(o.Signatures, result.Signature)
+	o.clearSliceInFlight()
+}
+
+func (o *TWAPOrder) clearSliceInFlight() {
+	o.SliceInFlight = false
+	o.SliceSignatures = nil
+	o.SliceSentAt = time.Time{}
+}
+
+func (o *TWAPOrder) save() error {
+	file, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a crash can't leave a truncated order
+	path := filepath.Join(GetExecutablePath(), twapOrderFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, file, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func (o *TWAPOrder) remove() error {
+	err := os.Remove(filepath.Join(GetExecutablePath(), twapOrderFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
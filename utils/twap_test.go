@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTWAPNextSliceSOL(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  TWAPOrder
+		want   float64
+		wantOK bool
+	}{
+		{
+			name:   "first slice",
+			order:  TWAPOrder{TotalSOL: 0.004, Slices: 4},
+			want:   0.001,
+			wantOK: true,
+		},
+		{
+			// The first slice paid 0.00204 SOL of rent, which doesn't shrink the rest
+			name:   "rent of the first slice",
+			order:  TWAPOrder{TotalSOL: 0.004, Slices: 4, SlicesDone: 1, SwappedSOL: 0.001, SpentSOL: 0.003044},
+			want:   0.001,
+			wantOK: true,
+		},
+		{
+			name:   "retried slice keeps the total",
+			order:  TWAPOrder{TotalSOL: 0.004, Slices: 4, SlicesDone: 2, SwappedSOL: 0.001},
+			want:   0.0015,
+			wantOK: true,
+		},
+		{
+			name:  "everything swapped",
+			order: TWAPOrder{TotalSOL: 0.004, Slices: 4, SlicesDone: 3, SwappedSOL: 0.0040001},
+		},
+		{
+			name:  "all slices done",
+			order: TWAPOrder{TotalSOL: 0.004, Slices: 4, SlicesDone: 4, SwappedSOL: 0.003},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.order.nextSliceSOL()
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("nextSliceSOL() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -2,11 +2,69 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const DefaultUnmineableBaseURL = "https://api.unminable.com/v4"
+
+const (
+	defaultUnmineableRetries    = 3
+	defaultUnmineableRetryDelay = 2 * time.Second
+	// Retry-After values above this are not waited for
+	maxUnmineableRetryDelay = time.Minute
+)
+
+// unMineable pages payouts, stop after this many pages
+const maxUnmineablePayoutPages = 20
+
+var (
+	// ErrUnknownAddress means unMineable has no account for the address and coin
+	ErrUnknownAddress = errors.New("unknown unMineable address")
+	// ErrRateLimited means the requests were throttled
+	ErrRateLimited = errors.New("unMineable rate limit reached")
+	// ErrServerError means unMineable failed to answer the request
+	ErrServerError = errors.New("unMineable server error")
+	// ErrUnreachable means the request or its response got lost on the network
+	ErrUnreachable = errors.New("unMineable unreachable")
+)
+
+// UnmineableError is a failed API request. It wraps one of the Err values
+// above when the cause is known, so callers can check it with errors.Is.
+type UnmineableError struct {
+	Kind       error
+	StatusCode int
+	Path       string
+	Message    string
+}
+
+func (e *UnmineableError) Error() string {
+	var sb strings.Builder
+	if e.Kind != nil {
+		sb.WriteString(e.Kind.Error())
+	} else {
+		sb.WriteString("unMineable request failed")
+	}
+	if e.StatusCode != 0 {
+		sb.WriteString(fmt.Sprintf(" (HTTP %d)", e.StatusCode))
+	}
+	if e.Message != "" {
+		sb.WriteString(": " + e.Message)
+	}
+	return sb.String()
+}
+
+func (e *UnmineableError) Unwrap() error {
+	return e.Kind
+}
+
 type UnmineableInfo struct {
 	Uuid             string
 	Balance          string
@@ -18,92 +76,356 @@ type UnmineableInfo struct {
 	Past30d          string
 }
 
-var (
-	unmineableInfo UnmineableInfo
-)
+// UnmineableWorker is one rig mining to the account
+type UnmineableWorker struct {
+	Name           string
+	Algorithm      string
+	Online         bool
+	ReportedHash   float64
+	CalculatedHash float64
+	LastShare      time.Time
+}
+
+// UnmineablePayout is one payment unMineable sent from the account
+type UnmineablePayout struct {
+	Amount float64   `json:"amount"`
+	Coin   string    `json:"coin"`
+	TxHash string    `json:"txHash"`
+	Date   time.Time `json:"date"`
+	Status string    `json:"status"`
+}
+
+// unmineableNumber accepts numbers the API sends either as JSON numbers or strings
+type unmineableNumber float64
 
-// GetUnmineableInfo fetches information from the Unmineable API
+func (n *unmineableNumber) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*n = 0
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return err
+	}
+	*n = unmineableNumber(value)
+	return nil
+}
+
+// UnmineableClient calls the unMineable API. Rate limited requests, server
+// errors and network errors are retried with exponential backoff.
+type UnmineableClient struct {
+	BaseURL    string
+	MaxRetries int
+	RetryDelay time.Duration
+	client     *http.Client
+}
+
+// NewUnmineableClient creates a client for the API at baseURL, the public API if empty
+func NewUnmineableClient(baseURL string) *UnmineableClient {
+	if baseURL == "" {
+		baseURL = DefaultUnmineableBaseURL
+	}
+	return &UnmineableClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		MaxRetries: defaultUnmineableRetries,
+		RetryDelay: defaultUnmineableRetryDelay,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// newUnmineableClientFromConfig creates a client for the configured API
+func newUnmineableClientFromConfig() *UnmineableClient {
+	config, err := ReadMiningConfigFile()
+	if err != nil {
+		LogToFile(fmt.Sprintf("Failed to read mining config, using the public unMineable API: %v", err))
+	}
+	return NewUnmineableClient(config.UnmineableBaseURL)
+}
+
+// GetUnmineableInfo fetches the account stats of the address from the configured API
 func GetUnmineableInfo(publicKey string, coin string) (*UnmineableInfo, error) {
-	url := fmt.Sprintf("https://api.unminable.com/v4/address/%s?coin=%s", publicKey, coin)
+	return newUnmineableClientFromConfig().GetInfo(publicKey, coin)
+}
 
-	resp, err := http.Get(url)
+// GetUnmineableWorkers fetches the workers of the address from the configured API
+func GetUnmineableWorkers(publicKey string, coin string) ([]UnmineableWorker, error) {
+	client := newUnmineableClientFromConfig()
+	uuid, err := client.GetAccountID(publicKey, coin)
 	if err != nil {
-		LogToFile(fmt.Sprintf("HTTP GET request failed: %v", err))
-		return nil, fmt.Errorf("HTTP GET request failed: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return client.GetWorkers(uuid)
+}
+
+// GetUnmineablePayouts fetches the payout history of the address from the configured API
+func GetUnmineablePayouts(publicKey string, coin string) ([]UnmineablePayout, error) {
+	client := newUnmineableClientFromConfig()
+	uuid, err := client.GetAccountID(publicKey, coin)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetPayouts(uuid, coin)
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// GetAccountID returns the account id of the address, ErrUnknownAddress if it has none
+func (c *UnmineableClient) GetAccountID(address string, coin string) (string, error) {
+	account, err := c.getAccount(address, coin)
 	if err != nil {
-		LogToFile(fmt.Sprintf("Failed to read response body: %v", err))
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return "", err
 	}
+	return account.Uuid, nil
+}
+
+type unmineableAccount struct {
+	Uuid    string `json:"uuid"`
+	AutoPay bool   `json:"auto"`
+}
 
-	var unmineableResp struct {
-		// Add fields based on the actual API response structure
-		// This is a placeholder and should be adjusted
-		Data struct {
-			Uuid    string `json:"uuid"`
-			AutoPay bool   `json:"auto"`
-			// Add other fields as needed
-		} `json:"data"`
+func (c *UnmineableClient) getAccount(address string, coin string) (*unmineableAccount, error) {
+	var account unmineableAccount
+	path := fmt.Sprintf("/address/%s?coin=%s", url.PathEscape(address), url.QueryEscape(coin))
+	if err := c.get(path, &account); err != nil {
+		return nil, err
+	}
+	// unMineable answers unknown addresses without an account
+	if account.Uuid == "" {
+		return nil, &UnmineableError{Kind: ErrUnknownAddress, Path: path, Message: fmt.Sprintf("no %s account for %s", coin, address)}
 	}
-	err = json.Unmarshal(body, &unmineableResp)
+	return &account, nil
+}
+
+// GetInfo fetches the balance, payout settings and rewards of the address
+func (c *UnmineableClient) GetInfo(address string, coin string) (*UnmineableInfo, error) {
+	// 1. Account of the address
+	account, err := c.getAccount(address, coin)
 	if err != nil {
-		LogToFile(fmt.Sprintf("Failed to unmarshal JSON: %v", err))
-		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return nil, err
+	}
+
+	// 2. Account stats
+	var stats struct {
+		Balance          string `json:"balance"`
+		PaymentThreshold string `json:"payment_threshold"`
+		Coin             string `json:"coin"`
+		Rewarded         struct {
+			Past24h string `json:"past_24h"`
+			Past7d  string `json:"past_7d"`
+			Past30d string `json:"past_30d"`
+		} `json:"rewarded"`
+	}
+	if err := c.get(fmt.Sprintf("/account/%s/stats", url.PathEscape(account.Uuid)), &stats); err != nil {
+		return nil, err
+	}
+
+	info := &UnmineableInfo{
+		Uuid:             account.Uuid,
+		AutoPay:          account.AutoPay,
+		Balance:          stats.Balance,
+		PaymentThreshold: stats.PaymentThreshold,
+		Coin:             stats.Coin,
+		Past24h:          stats.Rewarded.Past24h,
+		Past7d:           stats.Rewarded.Past7d,
+		Past30d:          stats.Rewarded.Past30d,
 	}
 
-	// Log the unmineableResp to the debug file
-	LogToFile(fmt.Sprintf("Unmineable Response: %+v", unmineableResp))
+	LogToFile(fmt.Sprintf("Fetched Unmineable Info: %+v", *info))
+	return info, nil
+}
+
+// GetWorkers fetches the workers of all algorithms of the account
+func (c *UnmineableClient) GetWorkers(uuid string) ([]UnmineableWorker, error) {
+	// Workers are grouped by algorithm
+	var groups map[string]struct {
+		Workers []struct {
+			Name   string           `json:"name"`
+			Online bool             `json:"online"`
+			Last   unmineableNumber `json:"last"`
+			Rhr    unmineableNumber `json:"rhr"`
+			Chr    unmineableNumber `json:"chr"`
+		} `json:"workers"`
+	}
+	if err := c.get(fmt.Sprintf("/account/%s/workers", url.PathEscape(uuid)), &groups); err != nil {
+		return nil, err
+	}
 
-	unmineableInfo = UnmineableInfo{
-		Uuid:    unmineableResp.Data.Uuid,
-		AutoPay: unmineableResp.Data.AutoPay,
+	var workers []UnmineableWorker
+	for algorithm, group := range groups {
+		for _, worker := range group.Workers {
+			w := UnmineableWorker{
+				Name:           worker.Name,
+				Algorithm:      algorithm,
+				Online:         worker.Online,
+				ReportedHash:   float64(worker.Rhr),
+				CalculatedHash: float64(worker.Chr),
+			}
+			// Last share time in milliseconds
+			if worker.Last > 0 {
+				w.LastShare = time.UnixMilli(int64(worker.Last))
+			}
+			workers = append(workers, w)
+		}
 	}
 
-	// Fetch additional information
-	url = fmt.Sprintf("https://api.unminable.com/v4/account/%s/stats", unmineableInfo.Uuid)
-	resp, err = http.Get(url)
+	sort.Slice(workers, func(i, j int) bool {
+		if workers[i].Algorithm != workers[j].Algorithm {
+			return workers[i].Algorithm < workers[j].Algorithm
+		}
+		return workers[i].Name < workers[j].Name
+	})
+
+	LogToFile(fmt.Sprintf("Fetched %d Unmineable workers", len(workers)))
+	return workers, nil
+}
+
+// GetPayouts fetches the payout history of the account, newest first
+func (c *UnmineableClient) GetPayouts(uuid string, coin string) ([]UnmineablePayout, error) {
+	var payouts []UnmineablePayout
+	for page := 1; page <= maxUnmineablePayoutPages; page++ {
+		var payments struct {
+			List []struct {
+				Amount    unmineableNumber `json:"amount"`
+				Coin      string           `json:"coin"`
+				Tx        string           `json:"tx"`
+				Timestamp unmineableNumber `json:"timestamp"`
+				Status    string           `json:"status"`
+			} `json:"list"`
+			TotalPages int `json:"total_pages"`
+		}
+		if err := c.get(fmt.Sprintf("/account/%s/payments?page=%d", url.PathEscape(uuid), page), &payments); err != nil {
+			return nil, err
+		}
+
+		for _, payment := range payments.List {
+			payout := UnmineablePayout{
+				Amount: float64(payment.Amount),
+				Coin:   payment.Coin,
+				TxHash: payment.Tx,
+				Status: payment.Status,
+			}
+			if payout.Coin == "" {
+				payout.Coin = coin
+			}
+			// Payment time in milliseconds
+			if payment.Timestamp > 0 {
+				payout.Date = time.UnixMilli(int64(payment.Timestamp))
+			}
+			payouts = append(payouts, payout)
+		}
+
+		if len(payments.List) == 0 || page >= payments.TotalPages {
+			break
+		}
+	}
+
+	sort.SliceStable(payouts, func(i, j int) bool {
+		return payouts[i].Date.After(payouts[j].Date)
+	})
+
+	LogToFile(fmt.Sprintf("Fetched %d Unmineable payouts", len(payouts)))
+	return payouts, nil
+}
+
+// get requests the path and decodes the data of the response into out,
+// retrying the errors that may pass
+func (c *UnmineableClient) get(path string, out interface{}) error {
+	delay := c.RetryDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		retryAfter, err = c.getOnce(path, out)
+		if err == nil || !unmineableRetryable(err) || attempt >= c.MaxRetries {
+			break
+		}
+
+		wait := delay
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		LogToFile(fmt.Sprintf("Unmineable request %s failed, retrying in %s: %v", path, wait, err))
+		time.Sleep(wait)
+		delay *= 2
+	}
 	if err != nil {
-		LogToFile(fmt.Sprintf("HTTP GET request failed: %v", err))
-		return nil, fmt.Errorf("HTTP GET request failed: %v", err)
+		LogToFile(fmt.Sprintf("Unmineable request %s failed: %v", path, err))
+	}
+	return err
+}
+
+// getOnce makes one request. It returns how long the server asked to wait
+// before retrying, if it did.
+func (c *UnmineableClient) getOnce(path string, out interface{}) (time.Duration, error) {
+	resp, err := c.client.Get(c.BaseURL + path)
+	if err != nil {
+		return 0, &UnmineableError{Kind: ErrUnreachable, Path: path, Message: fmt.Sprintf("HTTP GET request failed: %v", err)}
 	}
 	defer resp.Body.Close()
 
-	body, err = ioutil.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		LogToFile(fmt.Sprintf("Failed to read response body: %v", err))
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return 0, &UnmineableError{Kind: ErrUnreachable, Path: path, Message: fmt.Sprintf("failed to read response body: %v", err)}
 	}
 
-	var unmineableStatsResp struct {
-		Data struct {
-			Balance          string `json:"balance"`
-			PaymentThreshold string `json:"payment_threshold"`
-			Coin             string `json:"coin"`
-			Rewarded         struct {
-				Past24h string `json:"past_24h"`
-				Past7d  string `json:"past_7d"`
-				Past30d string `json:"past_30d"`
-			} `json:"rewarded"`
-		} `json:"data"`
+	var envelope struct {
+		Success *bool           `json:"success"`
+		Msg     string          `json:"msg"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	jsonErr := json.Unmarshal(body, &envelope)
+	message := envelope.Msg
+	if message == "" {
+		message = envelope.Message
 	}
 
-	err = json.Unmarshal(body, &unmineableStatsResp)
-	if err != nil {
-		LogToFile(fmt.Sprintf("Failed to unmarshal JSON: %v", err))
-		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	// 1. HTTP status
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return parseRetryAfter(resp.Header.Get("Retry-After")),
+			&UnmineableError{Kind: ErrRateLimited, StatusCode: resp.StatusCode, Path: path, Message: message}
+	case resp.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/address/"):
+		return 0, &UnmineableError{Kind: ErrUnknownAddress, StatusCode: resp.StatusCode, Path: path, Message: message}
+	case resp.StatusCode >= 500:
+		return 0, &UnmineableError{Kind: ErrServerError, StatusCode: resp.StatusCode, Path: path, Message: message}
+	case resp.StatusCode != http.StatusOK:
+		return 0, &UnmineableError{StatusCode: resp.StatusCode, Path: path, Message: message}
 	}
 
-	unmineableInfo.Balance = unmineableStatsResp.Data.Balance
-	unmineableInfo.PaymentThreshold = unmineableStatsResp.Data.PaymentThreshold
-	unmineableInfo.Coin = unmineableStatsResp.Data.Coin
-	unmineableInfo.Past24h = unmineableStatsResp.Data.Rewarded.Past24h
-	unmineableInfo.Past7d = unmineableStatsResp.Data.Rewarded.Past7d
-	unmineableInfo.Past30d = unmineableStatsResp.Data.Rewarded.Past30d
+	// 2. Success field of the body
+	if jsonErr != nil {
+		return 0, &UnmineableError{Path: path, Message: fmt.Sprintf("failed to unmarshal JSON: %v", jsonErr)}
+	}
+	if envelope.Success != nil && !*envelope.Success {
+		if strings.HasPrefix(path, "/address/") {
+			return 0, &UnmineableError{Kind: ErrUnknownAddress, StatusCode: resp.StatusCode, Path: path, Message: message}
+		}
+		return 0, &UnmineableError{StatusCode: resp.StatusCode, Path: path, Message: message}
+	}
+
+	// 3. Data
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return 0, nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return 0, &UnmineableError{Path: path, Message: fmt.Sprintf("failed to unmarshal JSON: %v", err)}
+	}
+	return 0, nil
+}
 
-	LogToFile(fmt.Sprintf("Fetched Unmineable Info: %+v", unmineableInfo))
+// unmineableRetryable reports whether a request may succeed when repeated
+func unmineableRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServerError) || errors.Is(err, ErrUnreachable)
+}
 
-	return &unmineableInfo, nil
+// parseRetryAfter reads a Retry-After header in seconds
+func parseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	delay := time.Duration(seconds) * time.Second
+	if delay > maxUnmineableRetryDelay {
+		delay = maxUnmineableRetryDelay
+	}
+	return delay
 }
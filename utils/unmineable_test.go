@@ -0,0 +1,530 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testPayoutAddress = "9wFFyRfZBsuAha4YcuxcXLKwMxJR43S7fPfQLusDBzvT"
+
+// fakeUnmineableAccount is an account served by the fake unMineable API
+type fakeUnmineableAccount struct {
+	Address  string
+	Coin     string
+	Uuid     string
+	AutoPay  bool
+	Info     UnmineableInfo
+	Workers  []UnmineableWorker
+	Payouts  []UnmineablePayout
+	PageSize int
+}
+
+// fakeUnmineableServer is a local stand-in for the unMineable API. It serves
+// the endpoints UnmineableClient uses from in-memory accounts and can be told
+// to rate limit or fail requests.
+type fakeUnmineableServer struct {
+	server *httptest.Server
+
+	mutex    sync.Mutex
+	accounts map[string]*fakeUnmineableAccount
+	// The next requests answered with 429 or 500
+	rateLimitNext   int
+	serverErrorNext int
+	requests        int
+}
+
+func newFakeUnmineableServer(t *testing.T) *fakeUnmineableServer {
+	fake := &fakeUnmineableServer{accounts: make(map[string]*fakeUnmineableAccount)}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+// URL is the base URL to pass to NewUnmineableClient
+func (f *fakeUnmineableServer) URL() string {
+	return f.server.URL + "/v4"
+}
+
+// client returns a client for the fake that retries without waiting
+func (f *fakeUnmineableServer) client() *UnmineableClient {
+	client := NewUnmineableClient(f.URL())
+	client.RetryDelay = time.Millisecond
+	return client
+}
+
+func (f *fakeUnmineableServer) AddAccount(account *fakeUnmineableAccount) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if account.Uuid == "" {
+		account.Uuid = fmt.Sprintf("fake-%d", len(f.accounts)+1)
+	}
+	f.accounts[account.Uuid] = account
+}
+
+// RateLimitNext answers the next n requests with 429 Too Many Requests
+func (f *fakeUnmineableServer) RateLimitNext(n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rateLimitNext = n
+}
+
+// FailNext answers the next n requests with 500 Internal Server Error
+func (f *fakeUnmineableServer) FailNext(n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.serverErrorNext = n
+}
+
+// Requests returns how many requests were served
+func (f *fakeUnmineableServer) Requests() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.requests
+}
+
+func (f *fakeUnmineableServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.requests++
+
+	// 1. Injected failures
+	if f.rateLimitNext > 0 {
+		f.rateLimitNext--
+		w.Header().Set("Retry-After", "0")
+		writeFakeUnmineableResponse(w, http.StatusTooManyRequests, false, "Too many requests", nil)
+		return
+	}
+	if f.serverErrorNext > 0 {
+		f.serverErrorNext--
+		writeFakeUnmineableResponse(w, http.StatusInternalServerError, false, "Internal server error", nil)
+		return
+	}
+
+	// 2. Routes
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v4"), "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "address":
+		f.handleAddress(w, parts[1], r.URL.Query().Get("coin"))
+	case len(parts) == 3 && parts[0] == "account":
+		account, ok := f.accounts[parts[1]]
+		if !ok {
+			writeFakeUnmineableResponse(w, http.StatusNotFound, false, "Account not found", nil)
+			return
+		}
+		switch parts[2] {
+		case "stats":
+			f.handleStats(w, account)
+		case "workers":
+			f.handleWorkers(w, account)
+		case "payments":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			f.handlePayments(w, account, page)
+		default:
+			writeFakeUnmineableResponse(w, http.StatusNotFound, false, "Not found", nil)
+		}
+	default:
+		writeFakeUnmineableResponse(w, http.StatusNotFound, false, "Not found", nil)
+	}
+}
+
+func (f *fakeUnmineableServer) handleAddress(w http.ResponseWriter, address string, coin string) {
+	for _, account := range f.accounts {
+		if account.Address == address && strings.EqualFold(account.Coin, coin) {
+			writeFakeUnmineableResponse(w, http.StatusOK, true, "", map[string]interface{}{
+				"uuid": account.Uuid,
+				"auto": account.AutoPay,
+			})
+			return
+		}
+	}
+	// Like unMineable, an unknown address is answered without an account
+	writeFakeUnmineableResponse(w, http.StatusOK, true, "", map[string]interface{}{})
+}
+
+func (f *fakeUnmineableServer) handleStats(w http.ResponseWriter, account *fakeUnmineableAccount) {
+	writeFakeUnmineableResponse(w, http.StatusOK, true, "", map[string]interface{}{
+		"balance":           account.Info.Balance,
+		"payment_threshold": account.Info.PaymentThreshold,
+		"coin":              account.Coin,
+		"rewarded": map[string]string{
+			"past_24h": account.Info.Past24h,
+			"past_7d":  account.Info.Past7d,
+			"past_30d": account.Info.Past30d,
+		},
+	})
+}
+
+func (f *fakeUnmineableServer) handleWorkers(w http.ResponseWriter, account *fakeUnmineableAccount) {
+	groups := make(map[string]map[string][]map[string]interface{})
+	for _, worker := range account.Workers {
+		if groups[worker.Algorithm] == nil {
+			groups[worker.Algorithm] = map[string][]map[string]interface{}{"workers": {}}
+		}
+		last := int64(0)
+		if !worker.LastShare.IsZero() {
+			last = worker.LastShare.UnixMilli()
+		}
+		groups[worker.Algorithm]["workers"] = append(groups[worker.Algorithm]["workers"], map[string]interface{}{
+			"name":   worker.Name,
+			"online": worker.Online,
+			"last":   last,
+			// The API sends hashrates as strings
+			"rhr": strconv.FormatFloat(worker.ReportedHash, 'f', -1, 64),
+			"chr": strconv.FormatFloat(worker.CalculatedHash, 'f', -1, 64),
+		})
+	}
+	writeFakeUnmineableResponse(w, http.StatusOK, true, "", groups)
+}
+
+func (f *fakeUnmineableServer) handlePayments(w http.ResponseWriter, account *fakeUnmineableAccount, page int) {
+	pageSize := account.PageSize
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if page < 1 {
+		page = 1
+	}
+	totalPages := (len(account.Payouts) + pageSize - 1) / pageSize
+
+	list := []map[string]interface{}{}
+	for i := (page - 1) * pageSize; i < page*pageSize && i < len(account.Payouts); i++ {
+		payout := account.Payouts[i]
+		list = append(list, map[string]interface{}{
+			"amount":    strconv.FormatFloat(payout.Amount, 'f', -1, 64),
+			"coin":      payout.Coin,
+			"tx":        payout.TxHash,
+			"timestamp": payout.Date.UnixMilli(),
+			"status":    payout.Status,
+		})
+	}
+	writeFakeUnmineableResponse(w, http.StatusOK, true, "", map[string]interface{}{
+		"list":        list,
+		"total_pages": totalPages,
+	})
+}
+
+func writeFakeUnmineableResponse(w http.ResponseWriter, status int, success bool, msg string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": success,
+		"msg":     msg,
+		"data":    data,
+	})
+}
+
+func newTestUnmineableAccount() *fakeUnmineableAccount {
+	return &fakeUnmineableAccount{
+		Address: testPayoutAddress,
+		Coin:    SOL,
+		Uuid:    "a1b2c3",
+		AutoPay: true,
+		Info: UnmineableInfo{
+			Balance:          "0.0123",
+			PaymentThreshold: "0.1",
+			Past24h:          "0.001",
+			Past7d:           "0.007",
+			Past30d:          "0.03",
+		},
+	}
+}
+
+func TestUnmineableGetInfo(t *testing.T) {
+	fake := newFakeUnmineableServer(t)
+	fake.AddAccount(newTestUnmineableAccount())
+
+	info, err := fake.client().GetInfo(testPayoutAddress, SOL)
+	if err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	want := UnmineableInfo{
+		Uuid:             "a1b2c3",
+		Balance:          "0.0123",
+		PaymentThreshold: "0.1",
+		AutoPay:          true,
+		Coin:             SOL,
+		Past24h:          "0.001",
+		Past7d:           "0.007",
+		Past30d:          "0.03",
+	}
+	if *info != want {
+		t.Fatalf("info = %+v, want %+v", *info, want)
+	}
+}
+
+func TestUnmineableUnknownAddress(t *testing.T) {
+	fake := newFakeUnmineableServer(t)
+	fake.AddAccount(newTestUnmineableAccount())
+
+	tests := []struct {
+		name    string
+		address string
+		coin    string
+	}{
+		{"unknown address", "11111111111111111111111111111111", SOL},
+		{"known address, other coin", testPayoutAddress, "XMR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := fake.Requests()
+			_, err := fake.client().GetInfo(tt.address, tt.coin)
+			if !errors.Is(err, ErrUnknownAddress) {
+				t.Fatalf("error = %v, want ErrUnknownAddress", err)
+			}
+			// Unknown addresses are not retried
+			if got := fake.Requests() - requests; got != 1 {
+				t.Fatalf("%d requests, want 1", got)
+			}
+		})
+	}
+}
+
+func TestUnmineableRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		rateLimitNext int
+		failNext      int
+		maxRetries    int
+		wantErr       error
+		wantStatus    int
+		wantRequests  int
+	}{
+		{
+			name:          "429 is retried",
+			rateLimitNext: 2,
+			maxRetries:    3,
+			// Two throttled, then the address and the stats
+			wantRequests: 4,
+		},
+		{
+			name:         "500 is retried",
+			failNext:     1,
+			maxRetries:   3,
+			wantRequests: 3,
+		},
+		{
+			name:         "500 gives up after the retries",
+			failNext:     10,
+			maxRetries:   3,
+			wantErr:      ErrServerError,
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 4,
+		},
+		{
+			name:          "429 gives up after the retries",
+			rateLimitNext: 10,
+			maxRetries:    1,
+			wantErr:       ErrRateLimited,
+			wantStatus:    http.StatusTooManyRequests,
+			wantRequests:  2,
+		},
+		{
+			name:         "no retries",
+			failNext:     1,
+			maxRetries:   0,
+			wantErr:      ErrServerError,
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeUnmineableServer(t)
+			fake.AddAccount(newTestUnmineableAccount())
+			fake.RateLimitNext(tt.rateLimitNext)
+			fake.FailNext(tt.failNext)
+
+			client := fake.client()
+			client.MaxRetries = tt.maxRetries
+			_, err := client.GetInfo(testPayoutAddress, SOL)
+
+			if got := fake.Requests(); got != tt.wantRequests {
+				t.Errorf("%d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			var apiErr *UnmineableError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus {
+				t.Fatalf("error = %#v, want an UnmineableError with status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestUnmineableErrorKinds(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       *UnmineableError
+		want      string
+		retryable bool
+	}{
+		{
+			name:      "rate limited",
+			err:       &UnmineableError{Kind: ErrRateLimited, StatusCode: 429, Message: "Too many requests"},
+			want:      "unMineable rate limit reached (HTTP 429): Too many requests",
+			retryable: true,
+		},
+		{
+			name:      "server error",
+			err:       &UnmineableError{Kind: ErrServerError, StatusCode: 502},
+			want:      "unMineable server error (HTTP 502)",
+			retryable: true,
+		},
+		{
+			name:      "unreachable",
+			err:       &UnmineableError{Kind: ErrUnreachable, Message: "connection refused"},
+			want:      "unMineable unreachable: connection refused",
+			retryable: true,
+		},
+		{
+			name: "unknown address",
+			err:  &UnmineableError{Kind: ErrUnknownAddress, Message: "no SOL account"},
+			want: "unknown unMineable address: no SOL account",
+		},
+		{
+			name: "other failure",
+			err:  &UnmineableError{StatusCode: 403, Message: "Forbidden"},
+			want: "unMineable request failed (HTTP 403): Forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+			wrapped := fmt.Errorf("failed to get payout history: %w", tt.err)
+			if tt.err.Kind != nil && !errors.Is(wrapped, tt.err.Kind) {
+				t.Errorf("errors.Is(%v, %v) = false", wrapped, tt.err.Kind)
+			}
+			if got := unmineableRetryable(wrapped); got != tt.retryable {
+				t.Errorf("retryable = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestUnmineableUnreachable(t *testing.T) {
+	fake := newFakeUnmineableServer(t)
+	url := fake.URL()
+	fake.server.Close()
+
+	client := NewUnmineableClient(url)
+	client.MaxRetries = 0
+	_, err := client.GetAccountID(testPayoutAddress, SOL)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("error = %v, want ErrUnreachable", err)
+	}
+}
+
+func TestUnmineableWorkersAndPayouts(t *testing.T) {
+	fake := newFakeUnmineableServer(t)
+	account := newTestUnmineableAccount()
+	lastShare := time.UnixMilli(1700000000000)
+	account.Workers = []UnmineableWorker{
+		{Name: "rig2", Algorithm: "randomx", Online: true, ReportedHash: 2500.5, CalculatedHash: 2400, LastShare: lastShare},
+		{Name: "rig1", Algorithm: "randomx", Online: false},
+		{Name: "gpu", Algorithm: "etchash", Online: true, ReportedHash: 60e6},
+	}
+	day := 24 * time.Hour
+	start := time.UnixMilli(1700000000000)
+	for i := 0; i < 5; i++ {
+		account.Payouts = append(account.Payouts, UnmineablePayout{
+			Amount: 0.1,
+			Coin:   SOL,
+			TxHash: fmt.Sprintf("tx%d", i),
+			Date:   start.Add(time.Duration(i) * day),
+			Status: "paid",
+		})
+	}
+	account.PageSize = 2
+	fake.AddAccount(account)
+
+	client := fake.client()
+
+	workers, err := client.GetWorkers(account.Uuid)
+	if err != nil {
+		t.Fatalf("GetWorkers: %v", err)
+	}
+	wantWorkers := []string{"etchash/gpu", "randomx/rig1", "randomx/rig2"}
+	if len(workers) != len(wantWorkers) {
+		t.Fatalf("%d workers, want %d", len(workers), len(wantWorkers))
+	}
+	for i, worker := range workers {
+		if got := worker.Algorithm + "/" + worker.Name; got != wantWorkers[i] {
+			t.Errorf("worker %d = %s, want %s", i, got, wantWorkers[i])
+		}
+	}
+	if rig2 := workers[2]; rig2.ReportedHash != 2500.5 || !rig2.LastShare.Equal(lastShare) {
+		t.Errorf("rig2 = %+v", rig2)
+	}
+
+	payouts, err := client.GetPayouts(account.Uuid, SOL)
+	if err != nil {
+		t.Fatalf("GetPayouts: %v", err)
+	}
+	if len(payouts) != 5 {
+		t.Fatalf("%d payouts from 3 pages, want 5", len(payouts))
+	}
+	for i, payout := range payouts {
+		if want := fmt.Sprintf("tx%d", 4-i); payout.TxHash != want {
+			t.Errorf("payout %d = %s, want %s newest first", i, payout.TxHash, want)
+		}
+	}
+}
+
+func TestUnmineableBaseURL(t *testing.T) {
+	if got := NewUnmineableClient("").BaseURL; got != DefaultUnmineableBaseURL {
+		t.Errorf("default BaseURL = %q, want %q", got, DefaultUnmineableBaseURL)
+	}
+	if got := NewUnmineableClient("http://localhost:8080/v4/").BaseURL; got != "http://localhost:8080/v4" {
+		t.Errorf("BaseURL = %q, want the trailing slash trimmed", got)
+	}
+
+	// The mining config points the app at another API
+	fake := newFakeUnmineableServer(t)
+	fake.AddAccount(newTestUnmineableAccount())
+
+	configPath := filepath.Join(GetExecutablePath(), miningConfigFileName)
+	if _, err := os.Stat(configPath); err == nil {
+		t.Skipf("%s exists, not overwriting it", configPath)
+	}
+	config := defaultMiningConfig()
+	config.UnmineableBaseURL = fake.URL()
+	file, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(configPath)
+
+	info, err := GetUnmineableInfo(testPayoutAddress, SOL)
+	if err != nil {
+		t.Fatalf("GetUnmineableInfo: %v", err)
+	}
+	if info.Uuid != "a1b2c3" || fake.Requests() != 2 {
+		t.Fatalf("info = %+v after %d requests, want it from the configured API", *info, fake.Requests())
+	}
+}
@@ -278,7 +278,7 @@ func VerifyPassword(password string) bool {
 	walletFilePath := ""
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".solXENwallet") {
-			walletFilePath = filepath.Join("wallet", file.Name())
+			walletFilePath = filepath.Join(GetExecutablePath(), "wallet", file.Name())
 		}
 	}
 
@@ -516,7 +516,7 @@ func GetSOLBalance(publicKey string) (float64, error) {
 	LogToFile("Starting to fetch SOL balance")
 
 	// Prepare the RPC request
-	rpcURL := "https://api.mainnet-beta.solana.com"
+	rpcURL := SolanaRPCURL
 	payload := []byte(`{
         "jsonrpc": "2.0",
         "id": 1,
@@ -557,7 +557,7 @@ func GetWalletTokenBalances(publicKey string) ([]TokenBalance, error) {
 	LogToFile("Starting to fetch wallet token balances")
 
 	// Prepare the RPC request
-	rpcURL := "https://api.mainnet-beta.solana.com"
+	rpcURL := SolanaRPCURL
 	payload := []byte(`{
         "jsonrpc": "2.0",
         "id": 1,
@@ -607,11 +607,10 @@ func GetWalletTokenBalances(publicKey string) ([]TokenBalance, error) {
 		return nil, err
 	}
 
-	targetMints := map[string]bool{
-		"6f8deE148nynnSiWshA9vLydEbJGpDeKh5G4PRgjmzG7": true,
-		"7UN8WkBumTUCofVPXCPjNWQ6msQhzrg9tFQRP48Nmw5V": true,
-		"5px3a5LWR6CmiYX3ktpNnGYiEypfDdemRd74GDYbsJ2H": true,
-		"oreoU2P8bN6jkk3jbaiVxYnG1dCXcYxwhwyK9jSybcp":  true,
+	enabledTokens := GetEnabledTokens()
+	targetMints := make(map[string]bool)
+	for _, token := range enabledTokens {
+		targetMints[token.Mint] = true
 	}
 
 	var balances []TokenBalance
@@ -633,11 +632,11 @@ func GetWalletTokenBalances(publicKey string) ([]TokenBalance, error) {
 		}
 	}
 
-	if len(balances) == 0 {
+	if len(balances) == 0 && len(enabledTokens) > 0 {
 		LogToFile("No balances found for the specified tokens, returning default info")
 		balances = append(balances, TokenBalance{
-			Mint:    "6f8deE148nynnSiWshA9vLydEbJGpDeKh5G4PRgjmzG7",
-			Symbol:  "solXEN",
+			Mint:    enabledTokens[0].Mint,
+			Symbol:  enabledTokens[0].Symbol,
 			Balance: 0,
 		})
 	}
@@ -651,18 +650,8 @@ func GetWalletTokenBalances(publicKey string) ([]TokenBalance, error) {
 }
 
 func getTokenSymbol(mint string) string {
-	switch mint {
-	case "6f8deE148nynnSiWshA9vLydEbJGpDeKh5G4PRgjmzG7":
-		return "solXEN"
-	case "EEqrab5tdnVdZv7a4AUAvGehDAtM8gWd7szwfyYbmGkM":
-		return "OG solXEN"
-	case "7UN8WkBumTUCofVPXCPjNWQ6msQhzrg9tFQRP48Nmw5V":
-		return "xencat"
-	case "5px3a5LWR6CmiYX3ktpNnGYiEypfDdemRd74GDYbsJ2H":
-		return "PV"
-	case "oreoU2P8bN6jkk3jbaiVxYnG1dCXcYxwhwyK9jSybcp":
-		return "ORE"
-	default:
-		return "Unknown"
+	if token, ok := lookupTokenByMint(mint); ok {
+		return token.Symbol
 	}
+	return "Unknown"
 }
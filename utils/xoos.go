@@ -2,17 +2,38 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
 // var GLOBAL_WORK_DIR string
 
+const (
+	DefaultSlippageBps       = 50
+	DefaultMaxPriceImpactPct = 1.0
+	DefaultTWAPWindow        = "1h"
+)
+
 type SolXENConfig struct {
 	// AutoHarvestActive bool    `json:"autoHarvestActive"`
 	SOLPerHarvest   float64 `json:"solPerHarvest"`
 	TokenToHarvest  string  `json:"tokenToHarvest"`
 	HarvestInterval string  `json:"harvestInterval"`
+	SlippageBps     int     `json:"slippageBps"`
+	// Price impact limit in percent, 0 is no limit
+	MaxPriceImpactPct float64 `json:"maxPriceImpactPct"`
+	// Optional price conditions, 0 turns a condition off
+	MaxBuyPriceSOL  float64 `json:"maxBuyPriceSOL"`
+	MinDropPct      float64 `json:"minDropPct"`
+	DropWindowHours int     `json:"dropWindowHours"`
+	// TWAP mode splits each harvest into slices over a window, 1 slice means off
+	TWAPSlices int    `json:"twapSlices"`
+	TWAPWindow string `json:"twapWindow"`
+	// Swap backend, "jupiter" or the offline "stub"
+	SwapBackend    string `json:"swapBackend"`
+	JupiterBaseURL string `json:"jupiterBaseURL"`
+	JupiterAPIKey  string `json:"jupiterAPIKey"`
 	// HarvestBurn     string  `json:"harvestBurn"`
 }
 
@@ -28,6 +49,7 @@ func XoosInit() {
 	// GLOBAL_WORK_DIR = getExecutablePath()
 	initFileLogger()
 	initSolXENConfig()
+	loadTokenRegistry()
 }
 
 func initSolXENConfig() {
@@ -36,9 +58,13 @@ func initSolXENConfig() {
 		// If file doesn't exist, create a default one
 		defaultConfig := SolXENConfig{
 			// AutoHarvestActive: true,
-			SOLPerHarvest:   0.001,
-			TokenToHarvest:  "solXEN",
-			HarvestInterval: "Off",
+			SOLPerHarvest:     0.001,
+			TokenToHarvest:    "solXEN",
+			HarvestInterval:   "Off",
+			SlippageBps:       DefaultSlippageBps,
+			MaxPriceImpactPct: DefaultMaxPriceImpactPct,
+			TWAPSlices:        1,
+			TWAPWindow:        DefaultTWAPWindow,
 			// HarvestBurn:     "Off",
 		}
 		err = WriteSolXENConfigFile(defaultConfig)
@@ -55,16 +81,39 @@ func ReadSolXENConfigFile() (SolXENConfig, error) {
 		return SolXENConfig{}, err
 	}
 
-	var config SolXENConfig
+	// A price impact limit missing from the file keeps its default, 0 is no limit
+	config := SolXENConfig{MaxPriceImpactPct: DefaultMaxPriceImpactPct}
 	err = json.Unmarshal(file, &config)
 	if err != nil {
 		return SolXENConfig{}, err
 	}
 
+	// Config files written before these settings existed
+	if config.SlippageBps <= 0 {
+		config.SlippageBps = DefaultSlippageBps
+	}
+	if config.MaxPriceImpactPct < 0 {
+		config.MaxPriceImpactPct = DefaultMaxPriceImpactPct
+	}
+	if config.TWAPSlices <= 0 {
+		config.TWAPSlices = 1
+	}
+	if config.TWAPWindow == "" {
+		config.TWAPWindow = DefaultTWAPWindow
+	}
+	// A longer drop window could never be met
+	if config.DropWindowHours > MaxDropWindowHours {
+		config.DropWindowHours = MaxDropWindowHours
+	}
+
 	return config, nil
 }
 
 func WriteSolXENConfigFile(config SolXENConfig) error {
+	if config.DropWindowHours > MaxDropWindowHours {
+		return fmt.Errorf("drop window can be at most %dh, the price history is kept that long", MaxDropWindowHours)
+	}
+
 	configPath := filepath.Join(GetExecutablePath(), "solXENconfig.json")
 	file, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
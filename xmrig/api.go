@@ -0,0 +1,210 @@
+package xenblocks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"xoon/utils"
+)
+
+// The XMRig HTTP API is only served on localhost
+const (
+	API_HOST = "127.0.0.1"
+	API_PORT = 18088
+)
+
+// apiToken protects the API from other local programs, a new one is made
+// every time the app starts
+var apiToken = newAPIToken()
+
+func newAPIToken() string {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		utils.LogToFile(fmt.Sprintf("Failed to create XMRig API token, the API stays off: %v", err))
+		return ""
+	}
+	return hex.EncodeToString(token)
+}
+
+// apiArgs enables the HTTP API on the XMRig command line
+func apiArgs() []string {
+	if apiToken == "" {
+		return nil
+	}
+	return []string{
+		"--http-enabled",
+		"--http-host", API_HOST,
+		"--http-port", strconv.Itoa(API_PORT),
+		"--http-access-token", apiToken,
+	}
+}
+
+// Hashrates are reported for 10s, 60s and 15m, null while not known yet
+type apiHashrates []*float64
+
+func (h apiHashrates) get(i int) float64 {
+	if i < len(h) && h[i] != nil {
+		return *h[i]
+	}
+	return 0
+}
+
+// apiSummary is the part of /2/summary shown in the app
+type apiSummary struct {
+	Version  string `json:"version"`
+	Uptime   int64  `json:"uptime"`
+	Algo     string `json:"algo"`
+	Hashrate struct {
+		Total   apiHashrates `json:"total"`
+		Highest *float64     `json:"highest"`
+	} `json:"hashrate"`
+	Results struct {
+		DiffCurrent int64 `json:"diff_current"`
+		SharesGood  int   `json:"shares_good"`
+		SharesTotal int   `json:"shares_total"`
+	} `json:"results"`
+	Connection struct {
+		Pool     string `json:"pool"`
+		Uptime   int64  `json:"uptime"`
+		Ping     int64  `json:"ping"`
+		Failures int    `json:"failures"`
+		Accepted int    `json:"accepted"`
+		Rejected int    `json:"rejected"`
+		Diff     int64  `json:"diff"`
+	} `json:"connection"`
+}
+
+// apiBackend is a backend of /2/backends, the CPU one has the threads
+type apiBackend struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+	Threads []struct {
+		Affinity int          `json:"affinity"`
+		Hashrate apiHashrates `json:"hashrate"`
+	} `json:"threads"`
+}
+
+// ThreadStats is the hashrate of a mining thread in H/s
+type ThreadStats struct {
+	// CPU the thread is pinned to, -1 if none
+	Affinity    int
+	Hashrate10s float64
+	Hashrate60s float64
+	Hashrate15m float64
+}
+
+// APIStats are the mining figures reported by the XMRig API. Hashrates
+// are in H/s and 0 while XMRig hasn't measured them yet.
+type APIStats struct {
+	Version     string
+	Algorithm   string
+	Uptime      time.Duration
+	Hashrate10s float64
+	Hashrate60s float64
+	Hashrate15m float64
+	HashrateMax float64
+	Accepted    int
+	Rejected    int
+	// Difficulty of the current pool job
+	Difficulty  int64
+	Pool        string
+	PoolLatency time.Duration
+	Threads     []ThreadStats
+}
+
+// APIClient reads the stats of the running XMRig from its HTTP API
+type APIClient struct {
+	BaseURL string
+	Token   string
+	client  *http.Client
+}
+
+// NewAPIClient creates a client for the API of the XMRig started by the app
+func NewAPIClient() *APIClient {
+	return &APIClient{
+		BaseURL: fmt.Sprintf("http://%s:%d", API_HOST, API_PORT),
+		Token:   apiToken,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// GetStats polls /2/summary and /2/backends
+func (c *APIClient) GetStats() (*APIStats, error) {
+	if c.Token == "" {
+		return nil, errors.New("XMRig API is off")
+	}
+
+	// 1. Hashrate, shares and pool
+	var summary apiSummary
+	if err := c.get("/2/summary", &summary); err != nil {
+		return nil, err
+	}
+
+	stats := &APIStats{
+		Version:     summary.Version,
+		Algorithm:   summary.Algo,
+		Uptime:      time.Duration(summary.Uptime) * time.Second,
+		Hashrate10s: summary.Hashrate.Total.get(0),
+		Hashrate60s: summary.Hashrate.Total.get(1),
+		Hashrate15m: summary.Hashrate.Total.get(2),
+		Accepted:    summary.Connection.Accepted,
+		Rejected:    summary.Connection.Rejected,
+		Difficulty:  summary.Connection.Diff,
+		Pool:        summary.Connection.Pool,
+		PoolLatency: time.Duration(summary.Connection.Ping) * time.Millisecond,
+	}
+	if summary.Hashrate.Highest != nil {
+		stats.HashrateMax = *summary.Hashrate.Highest
+	}
+	if stats.Difficulty == 0 {
+		stats.Difficulty = summary.Results.DiffCurrent
+	}
+
+	// 2. Per thread rates of the CPU backend
+	var backends []apiBackend
+	if err := c.get("/2/backends", &backends); err != nil {
+		return nil, err
+	}
+	for _, backend := range backends {
+		if backend.Type != "cpu" || !backend.Enabled {
+			continue
+		}
+		for _, thread := range backend.Threads {
+			stats.Threads = append(stats.Threads, ThreadStats{
+				Affinity:    thread.Affinity,
+				Hashrate10s: thread.Hashrate.get(0),
+				Hashrate60s: thread.Hashrate.get(1),
+				Hashrate15m: thread.Hashrate.get(2),
+			})
+		}
+	}
+
+	return stats, nil
+}
+
+func (c *APIClient) get(path string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("XMRig API unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("XMRig API %s returned %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("error decoding XMRig API %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package xenblocks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// /2/summary of XMRig 6.21.0 mining GhostRider on unMineable
+const testSummaryJSON = `{
+    "id": "4c5c1f1d3d3f2a6b",
+    "worker_id": "xoon",
+    "uptime": 3725,
+    "restricted": true,
+    "resources": {
+        "memory": {"free": 9013768192, "total": 16624193536, "resident_set_memory": 36085760},
+        "load_average": [3.61, 3.44, 3.2],
+        "hardware_concurrency": 8
+    },
+    "features": ["api", "asm", "http", "hwloc", "tls", "opencl", "cuda"],
+    "results": {
+        "diff_current": 160001,
+        "shares_good": 42,
+        "shares_total": 43,
+        "avg_time": 86,
+        "avg_time_ms": 86604,
+        "hashes_total": 6878820,
+        "best": [8351521, 3311140, 1877421, 1366823, 1099372, 953417, 741893, 610420, 598013, 531274],
+        "error_log": []
+    },
+    "algo": "gr",
+    "connection": {
+        "pool": "ghostrider.unmineable.com:3333",
+        "ip": "51.195.249.3",
+        "uptime": 3712,
+        "uptime_ms": 3712881,
+        "ping": 38,
+        "failures": 0,
+        "tls": null,
+        "tls-fingerprint": null,
+        "algo": "gr",
+        "diff": 160001,
+        "accepted": 42,
+        "rejected": 1,
+        "avg_time": 86,
+        "avg_time_ms": 86604,
+        "hashes_total": 6878820,
+        "error_log": []
+    },
+    "version": "6.21.0",
+    "kind": "miner",
+    "ua": "XMRig/6.21.0 (Linux x86_64) libuv/1.44.2 gcc/9.4.0",
+    "cpu": {
+        "brand": "AMD Ryzen 7 5800X 8-Core Processor",
+        "family": 25,
+        "model": 33,
+        "stepping": 0,
+        "proc_info": 10555152,
+        "aes": true,
+        "avx2": true,
+        "x64": true,
+        "64_bit": true,
+        "l2": 4194304,
+        "l3": 33554432,
+        "cores": 8,
+        "threads": 8,
+        "packages": 1,
+        "nodes": 1,
+        "backend": "hwloc/2.9.0",
+        "msr": "ryzen_19h",
+        "assembly": "ryzen",
+        "arch": "x86_64",
+        "flags": ["aes", "avx", "avx2", "bmi2", "osxsave", "pdpe1gb", "sse2", "ssse3", "sse4.1", "popcnt", "cat_l3"]
+    },
+    "donate_level": 1,
+    "paused": false,
+    "algorithms": ["cn/0", "cn/1", "cn/2", "cn/r", "gr", "rx/0", "rx/wow", "argon2/chukwav2"],
+    "hashrate": {
+        "total": [1857.42, 1843.9, null],
+        "highest": 1902.15,
+        "threads": [[464.5, 461.2, null], [463.1, 460.8, null], [465.0, 461.4, null], [464.8, 460.5, null]]
+    },
+    "hugepages": [1200, 1200]
+}`
+
+// /2/backends of the same XMRig, the GPU backends are off
+const testBackendsJSON = `[
+    {
+        "type": "cpu",
+        "enabled": true,
+        "algo": "gr",
+        "profile": "gr",
+        "hw-aes": true,
+        "priority": -1,
+        "msr": true,
+        "asm": "ryzen",
+        "argon2-impl": null,
+        "hugepages": [1200, 1200],
+        "memory": 4915200,
+        "hashrate": [1857.42, 1843.9, null],
+        "threads": [
+            {"intensity": 1, "affinity": 0, "av": 1, "hashrate": [464.5, 461.2, null]},
+            {"intensity": 1, "affinity": 2, "av": 1, "hashrate": [463.1, 460.8, null]},
+            {"intensity": 1, "affinity": 4, "av": 1, "hashrate": [465.0, 461.4, null]},
+            {"intensity": 1, "affinity": -1, "av": 1, "hashrate": [null, null, null]}
+        ]
+    },
+    {
+        "type": "opencl",
+        "enabled": false,
+        "algo": null,
+        "profile": null,
+        "platform": null,
+        "hashrate": [0.0, 0.0, 0.0],
+        "threads": []
+    },
+    {
+        "type": "cuda",
+        "enabled": false,
+        "algo": null,
+        "profile": null,
+        "versions": null,
+        "hashrate": [0.0, 0.0, 0.0],
+        "threads": []
+    }
+]`
+
+const testToken = "0123456789abcdef0123456789abcdef"
+
+// newTestAPIServer serves the captured answers to requests with the token
+func newTestAPIServer(t *testing.T, status int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			http.Error(w, `{"status":401,"error":"Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if status != http.StatusOK {
+			http.Error(w, "{}", status)
+			return
+		}
+		switch r.URL.Path {
+		case "/2/summary":
+			w.Write([]byte(testSummaryJSON))
+		case "/2/backends":
+			w.Write([]byte(testBackendsJSON))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAPIClient(baseURL string, token string) *APIClient {
+	return &APIClient{BaseURL: baseURL, Token: token, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func TestGetStats(t *testing.T) {
+	server := newTestAPIServer(t, http.StatusOK)
+
+	stats, err := newTestAPIClient(server.URL, testToken).GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+
+	if stats.Version != "6.21.0" || stats.Algorithm != "gr" || stats.Uptime != 3725*time.Second {
+		t.Errorf("version %q, algorithm %q, uptime %s", stats.Version, stats.Algorithm, stats.Uptime)
+	}
+	// The 15m rate is null while XMRig hasn't run for 15 minutes
+	if stats.Hashrate10s != 1857.42 || stats.Hashrate60s != 1843.9 || stats.Hashrate15m != 0 || stats.HashrateMax != 1902.15 {
+		t.Errorf("hashrates %v/%v/%v max %v", stats.Hashrate10s, stats.Hashrate60s, stats.Hashrate15m, stats.HashrateMax)
+	}
+	if stats.Accepted != 42 || stats.Rejected != 1 || stats.Difficulty != 160001 {
+		t.Errorf("shares %d/%d, difficulty %d", stats.Accepted, stats.Rejected, stats.Difficulty)
+	}
+	if stats.Pool != "ghostrider.unmineable.com:3333" || stats.PoolLatency != 38*time.Millisecond {
+		t.Errorf("pool %q, latency %s", stats.Pool, stats.PoolLatency)
+	}
+
+	// Only the threads of the CPU backend
+	want := []ThreadStats{
+		{Affinity: 0, Hashrate10s: 464.5, Hashrate60s: 461.2},
+		{Affinity: 2, Hashrate10s: 463.1, Hashrate60s: 460.8},
+		{Affinity: 4, Hashrate10s: 465.0, Hashrate60s: 461.4},
+		{Affinity: -1},
+	}
+	if len(stats.Threads) != len(want) {
+		t.Fatalf("%d threads, want %d", len(stats.Threads), len(want))
+	}
+	for i := range want {
+		if stats.Threads[i] != want[i] {
+			t.Errorf("thread %d = %+v, want %+v", i, stats.Threads[i], want[i])
+		}
+	}
+}
+
+func TestGetStatsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		token   string
+		wantErr string
+	}{
+		{"API off without a token", http.StatusOK, "", "XMRig API is off"},
+		{"wrong token", http.StatusOK, "wrong", "401 Unauthorized"},
+		{"server error", http.StatusInternalServerError, testToken, "500 Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestAPIServer(t, tt.status)
+			_, err := newTestAPIClient(server.URL, tt.token).GetStats()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
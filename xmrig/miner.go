@@ -1,195 +1,119 @@
 package xenblocks
 
 import (
-	"bufio"
-	"fmt"
-	"io"
-	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
-	"sync"
-	"time"
+	"xoon/miner"
 	"xoon/utils"
 
 	"github.com/rivo/tview"
 )
 
-var isMining bool = false
-
 // CPU algorithms
 var CPUAlgorithms = []string{"GhostRider", "RandomX"}
 
 // Mining ports
 var CPUMiningPorts = []string{"443", "3333", "13333", "80"}
 
-func StartMining(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc,
-	publicKey string, selectedThreads string, selectedAlgorithm string, selectedPort string, workerName string) {
+var (
+	// miner    speed 10s/60s/15m 1510.3 1507.9 n/a H/s max 1520.1 H/s
+	speedPattern = regexp.MustCompile(`speed 10s/60s/15m (\S+) (\S+) (\S+) (\w*H/s)`)
+	// cpu      accepted (12/1) diff 10000 (87 ms)
+	sharesPattern = regexp.MustCompile(`(?:accepted|rejected) \((\d+)/(\d+)\)`)
+)
 
-	isMining = true
+// Adapter runs XMRig on unMineable
+type Adapter struct{}
 
-	go func() {
-		var xenblocksMinerPath = filepath.Join(utils.GetExecutablePath(), XMRIG_MINER_DIR)
+func (Adapter) Name() string {
+	return "XMRig"
+}
 
-		var err = os.Chdir(xenblocksMinerPath)
-		if err != nil {
-			logMessage(logView, "Error changing to xenblocksMiner directory: "+err.Error())
-			return
-		}
+func (Adapter) Dir() string {
+	return XMRIG_MINER_DIR
+}
 
-		// Set algorithm and host based on selectedAlgorithm
-		var algorithm, host string
-		switch selectedAlgorithm {
-		case "GhostRider":
-			algorithm = "gr"
-			host = "ghostrider.unmineable.com"
-		case "RandomX":
-			algorithm = "rx"
-			host = "rx.unmineable.com"
-		default:
-			// Default to GhostRider if unknown algorithm is provided
-			algorithm = "gr"
-			host = "ghostrider.unmineable.com"
-		}
+func (Adapter) Executable() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join("xmrig-6.22.0", "xmrig.exe")
+	}
+	return filepath.Join("xmrig-6.22.0", "xmrig")
+}
 
-		// Construct the mining address
-		miningAddress := host + ":" + selectedPort
-		if selectedPort == "443" {
-			miningAddress = "stratum+ssl://" + miningAddress
-		}
+func (Adapter) Algorithms() []string {
+	return CPUAlgorithms
+}
 
-		// Construct the arguments slice
-		args := []string{
-			"-a", algorithm,
-			"-t", selectedThreads,
-			"-o", miningAddress,
-			"-u", fmt.Sprintf("SOL:%s.%s#plxp-imd8", publicKey, workerName),
-			"-p", "x",
-		}
+func (Adapter) Ports() []string {
+	return CPUMiningPorts
+}
 
-		var executableName string
-		if runtime.GOOS == "windows" {
-			executableName = ".\\xmrig-6.22.0\\xmrig.exe"
-		} else {
-			executableName = "./xmrig-6.22.0/xmrig"
-		}
+func (Adapter) UsesThreads() bool {
+	return true
+}
 
-		cmd := exec.Command(executableName, args...)
+func (Adapter) Args(config miner.Config) []string {
+	// Set algorithm and host based on the selected algorithm
+	var algorithm, host string
+	switch config.Algorithm {
+	case "GhostRider":
+		algorithm = "gr"
+		host = "ghostrider.unmineable.com"
+	case "RandomX":
+		algorithm = "rx"
+		host = "rx.unmineable.com"
+	default:
+		// Default to GhostRider if unknown algorithm is provided
+		algorithm = "gr"
+		host = "ghostrider.unmineable.com"
+	}
 
-		// Create pipes for both stdout and stderr
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			logMessage(logView, "Error creating StdoutPipe: "+err.Error())
-			return
-		}
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			logMessage(logView, "Error creating StderrPipe: "+err.Error())
-			return
-		}
+	// Construct the mining address
+	miningAddress := host + ":" + config.Port
+	if config.Port == "443" {
+		miningAddress = "stratum+ssl://" + miningAddress
+	}
 
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			logMessage(logView, "Error starting miner: "+err.Error())
-			return
-		}
+	args := []string{
+		"-a", algorithm,
+		"-t", config.Threads,
+		"-o", miningAddress,
+		"-u", config.User,
+		"-p", "x",
+	}
+	return append(args, apiArgs()...)
+}
 
-		var (
-			lastUpdateTime time.Time
-			mutex          sync.Mutex
-		)
-
-		logMessage(logView, "Debug: Start Mining function called")
-
-		// Function to read from a pipe and send to UI
-		readPipe := func(pipe io.Reader) {
-			reader := bufio.NewReader(pipe)
-			buffer := make([]byte, 1024)
-
-			logMessage(logView, "Debug: Starting to read pipe")
-
-			for {
-				n, err := reader.Read(buffer)
-				if err != nil {
-					if err == io.EOF {
-						logMessage(logView, "Debug: EOF reached, waiting...")
-						time.Sleep(100 * time.Millisecond)
-						continue
-					}
-					logMessage(logView, fmt.Sprintf("Error reading pipe: %v", err))
-					break
-				}
-
-				if n > 0 {
-					output := string(buffer[:n])
-					//logMessage(logView, fmt.Sprintf("Debug: Read %d bytes", n))
-
-					lines := strings.Split(output, "\n")
-					for _, line := range lines {
-						line = strings.TrimSpace(line)
-						if line != "" {
-							if strings.Contains(line, "Mining:") {
-								mutex.Lock()
-								now := time.Now()
-								if now.Sub(lastUpdateTime) >= 10*time.Second {
-									lastUpdateTime = now
-									logMessage(logView, line)
-								}
-								mutex.Unlock()
-							} else if strings.Contains(line, "Ecosystem") {
-								//skip
-							} else {
-								logMessage(logView, line)
-							}
-						}
-					}
-				} else {
-					logMessage(logView, "Debug: No data read")
-				}
+func (Adapter) ParseLine(line string, stats *miner.Stats) miner.LineKind {
+	if match := speedPattern.FindStringSubmatch(line); match != nil {
+		// The 10s rate, or the longer ones while it's not known yet
+		for _, value := range match[1:4] {
+			if hashrate, ok := miner.ParseHashrate(value, match[4]); ok {
+				stats.Hashrate = hashrate
+				break
 			}
 		}
-
-		// Start goroutines to read from stdout and stderr
-		go readPipe(stdout)
-		go readPipe(stderr)
-
-		// Wait for the command to finish
-		if err := cmd.Wait(); err != nil {
-			app.QueueUpdateDraw(func() {
-				logMessage(logView, "Miner exited with error: "+err.Error())
-			})
-		} else {
-			app.QueueUpdateDraw(func() {
-				logMessage(logView, "Mining completed successfully")
-			})
-		}
-	}()
-}
-
-func StopMining(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc) {
-	KillMiningProcess()
-	logMessage(logView, "Mining stopped")
-	isMining = false
-
-	// Change directory to parent
-	if err := os.Chdir(".."); err != nil {
-		logMessage(logView, "Error changing to parent directory: "+err.Error())
 	}
-}
+	if match := sharesPattern.FindStringSubmatch(line); match != nil {
+		accepted, _ := strconv.Atoi(match[1])
+		rejected, _ := strconv.Atoi(match[2])
+		stats.Accepted = accepted
+		stats.Rejected = rejected
+	}
 
-// KillMiningProcess stops all running xenblocksMiner processes
-func KillMiningProcess() {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("taskkill", "/F", "/IM", "xmrig*")
-	} else {
-		cmd = exec.Command("pkill", "-f", "xmrig")
+	switch {
+	case strings.Contains(line, "Mining:"):
+		return miner.LineReport
+	case strings.Contains(line, "Ecosystem"):
+		return miner.LineSkip
+	default:
+		return miner.LineShow
 	}
-	_ = cmd.Run()
 }
 
-// IsMining returns the current mining status
-func IsMining() bool {
-	return isMining
+func (Adapter) Install(app *tview.Application, logView *tview.TextView, logMessage utils.LogMessageFunc) {
+	InstallXmrig(app, logView, logMessage)
 }
@@ -0,0 +1,66 @@
+package xenblocks
+
+import (
+	"math"
+	"testing"
+	"xoon/miner"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		wantHashrate float64
+		wantAccepted int
+		wantRejected int
+	}{
+		{
+			name:         "10s rate",
+			lines:        []string{"[2024-05-01 10:00:00.123]  miner    speed 10s/60s/15m 2345.6 2301.2 n/a H/s max 2400.0 H/s"},
+			wantHashrate: 2345.6,
+		},
+		{
+			name:         "60s rate while the 10s one is not known",
+			lines:        []string{"[2024-05-01 10:00:00.123]  miner    speed 10s/60s/15m n/a 2301.2 n/a H/s max 2400.0 H/s"},
+			wantHashrate: 2301.2,
+		},
+		{
+			name:         "kH/s",
+			lines:        []string{"[2024-05-01 10:00:00.123]  miner    speed 10s/60s/15m 1.25 1.20 1.10 kH/s max 1.30 kH/s"},
+			wantHashrate: 1250,
+		},
+		{
+			name: "no rate known yet keeps the last one",
+			lines: []string{
+				"[2024-05-01 10:00:00.123]  miner    speed 10s/60s/15m 2345.6 n/a n/a H/s max 2400.0 H/s",
+				"[2024-05-01 10:00:10.123]  miner    speed 10s/60s/15m n/a n/a n/a H/s max n/a H/s",
+			},
+			wantHashrate: 2345.6,
+		},
+		{
+			name: "share totals",
+			lines: []string{
+				"[2024-05-01 10:00:00.123]  cpu      accepted (1/0) diff 120001 (45 ms)",
+				"[2024-05-01 10:00:30.123]  cpu      accepted (2/0) diff 120001 (44 ms)",
+				"[2024-05-01 10:01:00.123]  cpu      rejected (2/1) diff 120001 \"low difficulty share\" (46 ms)",
+			},
+			wantAccepted: 2,
+			wantRejected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats miner.Stats
+			for _, line := range tt.lines {
+				Adapter{}.ParseLine(line, &stats)
+			}
+			if math.Abs(stats.Hashrate-tt.wantHashrate) > 1e-6 {
+				t.Errorf("hashrate = %v, want %v", stats.Hashrate, tt.wantHashrate)
+			}
+			if stats.Accepted != tt.wantAccepted || stats.Rejected != tt.wantRejected {
+				t.Errorf("shares = %d/%d, want %d/%d", stats.Accepted, stats.Rejected, tt.wantAccepted, tt.wantRejected)
+			}
+		})
+	}
+}